@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 
+	"github.com/openshift/operator-framework-tooling/pkg/flags"
 	v1 "github.com/openshift/operator-framework-tooling/pkg/v1"
 	"github.com/sirupsen/logrus"
 )
@@ -16,8 +19,14 @@ func main() {
 	opts.Bind(flag.CommandLine)
 	flag.Parse()
 
+	if opts.PrintVersion {
+		fmt.Println(flags.Version)
+		return
+	}
+
 	if err := opts.Validate(); err != nil {
-		logger.WithError(err).Fatal("invalid options")
+		logger.WithError(err).Error("invalid options")
+		os.Exit(flags.ExitConfig)
 	}
 
 	logLevel, _ := logrus.ParseLevel(opts.LogLevel)
@@ -26,7 +35,18 @@ func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	if opts.Deadline > 0 {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithTimeout(ctx, opts.Deadline)
+		defer cancelDeadline()
+	}
+
 	if err := v1.Run(ctx, logger, opts); err != nil {
-		logrus.WithError(err).Fatal("failed to execute")
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logrus.WithError(err).Errorf("deadline exceeded: run did not complete within %s", opts.Deadline)
+			os.Exit(flags.ExitTransient)
+		}
+		logrus.WithError(err).Error("failed to execute")
+		os.Exit(flags.ExitCode(err))
 	}
 }
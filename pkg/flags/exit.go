@@ -0,0 +1,14 @@
+package flags
+
+import "github.com/openshift/operator-framework-tooling/pkg/internal"
+
+// The exit codes of the commands, which jobs can tell failures apart by. See internal.ExitCode.
+const (
+	ExitConfig    = int(internal.ExitConfig)
+	ExitTransient = int(internal.ExitTransient)
+)
+
+// ExitCode determines the code to exit with for the error that a run returned.
+func ExitCode(err error) int {
+	return int(internal.ExitCodeOf(err))
+}
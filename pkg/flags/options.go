@@ -1,11 +1,24 @@
 package flags
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/openshift/operator-framework-tooling/pkg/internal/jira"
 	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/config/secret"
 	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
 )
 
 type Mode string
@@ -14,6 +27,12 @@ const (
 	Summarize   Mode = "summarize"
 	Synchronize Mode = "synchronize"
 	Publish     Mode = "publish"
+
+	ValidateConfig Mode = "validate-config"
+	Doctor         Mode = "doctor"
+	Lag            Mode = "lag"
+	CherryPickOne  Mode = "cherry-pick-one"
+	RewriteGoMod   Mode = "rewrite-gomod"
 )
 
 type FetchMode string
@@ -31,15 +50,42 @@ const (
 	DefaultPRAssignee = "openshift/openshift-team-operator-framework"
 
 	DefaultBaseBranch = "master"
+
+	// DefaultIssueRef is used in pull request titles when no issue is referenced.
+	DefaultIssueRef = "NO-ISSUE"
 )
 
+// Version is the version of this tool, set at build time with
+// -ldflags "-X github.com/openshift/operator-framework-tooling/pkg/flags.Version=<version>".
+var Version = "unknown"
+
+// issueRefRegex matches the issue references allowed in pull request titles, e.g. OCPBUGS-1234.
+var issueRefRegex = regexp.MustCompile(`^[A-Z][A-Z0-9]*-[0-9]+$`)
+
 type Options struct {
 	CommitFileOutput string
 	CommitFileInput  string
+	DetectOnly       bool
+	StrictPlan       bool
+	PathFilters      flagutil.Strings
 	Mode             string
 	LogLevel         string
 	FetchMode        string
 	FetchDir         string
+	FetchDepth       int
+	BatchFetch       bool
+	Offline          bool
+	ModuleCacheFile  string
+	GithubOutputFile string
+	GoProxy          string
+	GoFlags          string
+	GoBin            string
+	GoModRetries     int
+	Deadline         time.Duration
+	DumpScript       string
+	OTLPEndpoint     string
+	LagJSON          bool
+	PrintVersion     bool
 
 	DryRun       bool
 	GithubLogin  string
@@ -48,11 +94,41 @@ type Options struct {
 	GitName      string
 	GitEmail     string
 	GitSignoff   bool
+	AddCoauthor  bool
+	ResetDates   bool
+	GeneratedBy  bool
+	GitConfig    flagutil.Strings
 	Assign       string
+	CcOwners     bool
 	SelfApprove  bool
+	ForkOnly     bool
+	ResetFork    bool
+	NoForcePush  bool
 	PRBaseBranch string
+	BodyTemplate string
+	DiffStat     bool
+	IssueRef     string
+
+	JiraURL        string
+	JiraTokenPath  string
+	JiraIssue      string
+	JiraTransition string
 
 	DelayManifestGeneration bool
+	NoVendor                bool
+	MaxFileSize             int64
+	WarnLargeFiles          bool
+	VerifyBuild             bool
+	ConflictPatchDir        string
+	RequireSignedUpstream   bool
+	RequireSignedCarries    bool
+	AllowedSigners          string
+	GPGHome                 string
+	CommentOnUpdate         bool
+	GithubReadTokenPath     string
+
+	// committerDate is the committer date of the commits created with GitEnv, once pinned
+	committerDate time.Time
 
 	flagutil.GitHubOptions
 }
@@ -70,17 +146,36 @@ func DefaultOptions() Options {
 		Assign:                  DefaultPRAssignee,
 		SelfApprove:             false,
 		PRBaseBranch:            DefaultBaseBranch,
+		IssueRef:                DefaultIssueRef,
+		GithubOutputFile:        os.Getenv("GITHUB_OUTPUT"),
 		DelayManifestGeneration: false,
 	}
 }
 
 func (o *Options) Bind(fs *flag.FlagSet) {
-	fs.StringVar(&o.Mode, "mode", o.Mode, fmt.Sprintf("Operation Mode. One of %s", []Mode{Summarize, Synchronize, Publish}))
+	fs.StringVar(&o.Mode, "mode", o.Mode, fmt.Sprintf("Operation Mode. One of %s", []Mode{Summarize, Synchronize, Publish, ValidateConfig, Doctor, Lag, CherryPickOne, RewriteGoMod}))
 	fs.StringVar(&o.CommitFileOutput, "commits-output", o.CommitFileInput, "File to write commits data to after resolving what needs to be synced.")
 	fs.StringVar(&o.CommitFileInput, "commits-input", o.CommitFileOutput, "File to read commits data from in order to drive sync process.")
+	fs.Var(&o.PathFilters, "path-filter", "Only detect commits that touch this path, as a git pathspec relative to the root of the repository. May be repeated. A commit that changes both filtered and other paths is kept whole, but commits that only change other paths are skipped even when an included commit depends on them, which is warned about.")
+	fs.BoolVar(&o.StrictPlan, "strict-plan", o.StrictPlan, "Fail instead of warning when --commits-input was detected against upstream targets that have since moved.")
+	fs.BoolVar(&o.DetectOnly, "detect-only", o.DetectOnly, "Exit after detecting commits and writing them to --commits-output.")
 	fs.StringVar(&o.LogLevel, "log-level", o.LogLevel, "Logging level.")
 	fs.StringVar(&o.FetchMode, "fetch-mode", o.FetchMode, "Method to use for fetching from git remotes.")
 	fs.StringVar(&o.FetchDir, "fetch-dir", o.FetchDir, "Base directory for 'file' fetch mode.")
+	fs.BoolVar(&o.BatchFetch, "batch-fetch", o.BatchFetch, "Fetch the refs needed from each remote together, and skip fetching refs that were already fetched during the run.")
+	fs.BoolVar(&o.Offline, "offline", o.Offline, "In validate-config mode, skip checking that git remotes are reachable.")
+	fs.IntVar(&o.FetchDepth, "fetch-depth", o.FetchDepth, "Depth to use when fetching refs that do not need full history. If not specified, fetches full history.")
+	fs.StringVar(&o.GoProxy, "goproxy", o.GoProxy, "GOPROXY to use for go mod operations. If not specified, uses the ambient environment.")
+	fs.StringVar(&o.GoFlags, "goflags", o.GoFlags, "GOFLAGS to use for go mod operations. If not specified, uses the ambient environment.")
+	fs.IntVar(&o.GoModRetries, "gomod-retries", o.GoModRetries, "How many times to retry go mod commands that fail to download modules. Other failures are not retried.")
+	fs.DurationVar(&o.Deadline, "deadline", o.Deadline, "Maximum duration of the whole run, after which it is aborted. If not specified, the run is not bounded.")
+	fs.StringVar(&o.GoBin, "go-bin", o.GoBin, "Path to the go binary to use for go mod operations. If specified, GOTOOLCHAIN=local is set so that it is not switched for another toolchain. If not specified, uses go from the PATH.")
+	fs.StringVar(&o.DumpScript, "dump-script", o.DumpScript, "File to record every git, go and make command run into, as a shell script. Credentials are censored.")
+	fs.StringVar(&o.OTLPEndpoint, "otlp-endpoint", o.OTLPEndpoint, "OTLP/HTTP collector to export traces of the run's phases and commands to, e.g. http://localhost:4318. Secrets are censored. If not specified, no traces are recorded.")
+	fs.BoolVar(&o.PrintVersion, "version", o.PrintVersion, "Print the version of this tool and exit.")
+	fs.BoolVar(&o.LagJSON, "lag-json", o.LagJSON, "In lag mode, print JSON instead of a table.")
+	fs.StringVar(&o.ModuleCacheFile, "module-cache-file", o.ModuleCacheFile, "File to persist resolved module versions to across runs.")
+	fs.StringVar(&o.GithubOutputFile, "github-output-file", o.GithubOutputFile, "GitHub Actions step output file to write the target_sha, carry_count and changed outputs of the detection to. Defaults to $GITHUB_OUTPUT.")
 
 	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun, "Whether to actually create the pull request with github client")
 	fs.StringVar(&o.GithubLogin, "github-login", o.GithubLogin, "The GitHub username to use.")
@@ -89,19 +184,45 @@ func (o *Options) Bind(fs *flag.FlagSet) {
 	fs.StringVar(&o.GitName, "git-name", o.GitName, "The name to use on the git commit. Requires --git-email. If not specified, uses the system default.")
 	fs.StringVar(&o.GitEmail, "git-email", o.GitEmail, "The email to use on the git commit. Requires --git-name. If not specified, uses the system default.")
 	fs.BoolVar(&o.GitSignoff, "git-signoff", o.GitSignoff, "Whether to signoff the commit. (https://git-scm.com/docs/git-commit#Documentation/git-commit.txt---signoff)")
+	fs.BoolVar(&o.AddCoauthor, "add-coauthor", o.AddCoauthor, "Whether to add a Co-authored-by trailer for --git-name and --git-email to carried commits.")
+	fs.BoolVar(&o.ResetDates, "reset-dates", o.ResetDates, "Set the committer date of all the commits created by the run, cherry-picked or generated, to the time it started cherry-picking, so that the synchronized branch is monotonic. Author dates are kept.")
+	fs.BoolVar(&o.GeneratedBy, "generated-by-trailer", o.GeneratedBy, "Whether to add a Generated-by trailer with the version of this tool to the commits it generates.")
+	fs.Var(&o.GitConfig, "git-config", "Git config to set in each repository before modifying it, as key=value. May be repeated.")
 	fs.StringVar(&o.Assign, "assign", o.Assign, "The comma-delimited set of github usernames or group names to assign the created pull request to.")
+	fs.BoolVar(&o.CcOwners, "cc-owners", o.CcOwners, "Also cc the approvers from the downstream OWNERS files closest to the paths changed by the synchronized commits.")
 	fs.BoolVar(&o.SelfApprove, "self-approve", o.SelfApprove, "Self-approve the PR by adding the `approved` and `lgtm` labels. Requires write permissions on the repo.")
+	fs.BoolVar(&o.ForkOnly, "fork-only", o.ForkOnly, "In publish mode, only push to the fork of --github-login and print the URL to create the pull request at, instead of creating and labelling it through the GitHub API. For contributors without permissions in --org.")
+	fs.BoolVar(&o.ResetFork, "reset-fork-branch", o.ResetFork, "In publish mode, reset the branch in the fork to the downstream base before pushing the synchronization onto it as a fast-forward, so that the pull request only shows the synchronization even if the branch had diverged from the base. A push made to the branch while the run was going is not lost.")
+	fs.BoolVar(&o.NoForcePush, "no-force-push", o.NoForcePush, "In publish mode, never force-push the branch in the fork, failing instead when it has diverged from the synchronization.")
+	fs.BoolVar(&o.CommentOnUpdate, "comment-on-update", o.CommentOnUpdate, "When updating an existing pull request, comment with the changes since the previous synchronization.")
 	fs.StringVar(&o.PRBaseBranch, "pr-base-branch", o.PRBaseBranch, "The base branch to use for the pull request.")
+	fs.StringVar(&o.IssueRef, "issue-ref", o.IssueRef, "The issue to reference in the pull request title, e.g. OCPBUGS-1234.")
+	fs.StringVar(&o.JiraURL, "jira-url", o.JiraURL, "Base URL of the Jira server to report published pull requests to. If not specified, nothing is reported.")
+	fs.StringVar(&o.JiraTokenPath, "jira-token-path", o.JiraTokenPath, "Path to a Jira personal access token. Required with --jira-url.")
+	fs.StringVar(&o.JiraIssue, "jira-issue", o.JiraIssue, "The Jira issue to comment on with the pull requests, targets and carries once published, e.g. OCPBUGS-1234. Required with --jira-url.")
+	fs.StringVar(&o.JiraTransition, "jira-transition", o.JiraTransition, "The name of the transition to move --jira-issue through after commenting, e.g. POST. If not specified, the issue is not transitioned.")
+	fs.StringVar(&o.BodyTemplate, "body-template", o.BodyTemplate, "Go text/template file to render the pull request body from. The template receives the target commit, the synchronized or carried commits, the assignees, the diff stat with --include-diffstat, and the rendered commit tables. If not specified, uses the default body.")
+	fs.BoolVar(&o.DiffStat, "include-diffstat", o.DiffStat, "Include the number of files and lines changed by the synchronization in the pull request body, with the changes to vendor/ counted separately.")
+	fs.BoolVar(&o.NoVendor, "no-vendor", o.NoVendor, "Skip go mod vendor for repositories that do not commit a vendor directory.")
+	fs.Int64Var(&o.MaxFileSize, "max-file-size", o.MaxFileSize, "Maximum size in bytes of the files added or modified by each cherry-picked commit. Larger files abort the run. If not specified, file sizes are not checked.")
+	fs.BoolVar(&o.WarnLargeFiles, "warn-large-files", o.WarnLargeFiles, "Warn instead of aborting when a cherry-picked commit exceeds --max-file-size.")
+	fs.BoolVar(&o.VerifyBuild, "verify-build", o.VerifyBuild, "After synchronizing, also build the vendored tree of each synchronized module with go build -mod=vendor, on top of checking that vendor/modules.txt is consistent with go.mod and go.sum.")
+	fs.StringVar(&o.ConflictPatchDir, "conflict-patch-dir", o.ConflictPatchDir, "Directory to write the patch of a carry whose cherry-pick conflicts to, along with the diff of the conflicts and a JSON report, before aborting the cherry-pick. For resolving conflicts offline when --pause-on-cherry-pick-error is not an option.")
+	fs.BoolVar(&o.RequireSignedUpstream, "require-signed-upstream", o.RequireSignedUpstream, "Fail unless the resolved upstream targets have a good signature from a key in the trust store given by --allowed-signers or --gpg-home.")
+	fs.BoolVar(&o.RequireSignedCarries, "require-signed-carries", o.RequireSignedCarries, "With --require-signed-upstream, also require a good signature on every commit to cherry-pick.")
+	fs.StringVar(&o.AllowedSigners, "allowed-signers", o.AllowedSigners, "Allowed signers file to verify SSH commit signatures against. If not specified, uses the git configuration.")
+	fs.StringVar(&o.GPGHome, "gpg-home", o.GPGHome, "GnuPG home directory holding the keyring to verify GPG commit signatures against. If not specified, uses the default GnuPG home.")
 	fs.BoolVar(&o.DelayManifestGeneration, "delay-manifest-generation", o.DelayManifestGeneration, "Delay manifest generation until the end.")
+	fs.StringVar(&o.GithubReadTokenPath, "github-read-token-path", o.GithubReadTokenPath, "Path to a read-only GitHub token, used for API reads when not publishing so that the write-scoped token is not needed.")
 	o.GitHubOptions.AddFlags(fs)
 	o.GitHubOptions.AllowAnonymous = true
 }
 
 func (o *Options) Validate() error {
 	switch Mode(o.Mode) {
-	case Summarize, Synchronize, Publish:
+	case Summarize, Synchronize, Publish, ValidateConfig, Doctor, Lag, CherryPickOne, RewriteGoMod:
 	default:
-		return fmt.Errorf("--mode must be one of %v", []Mode{Summarize, Synchronize, Publish})
+		return fmt.Errorf("--mode must be one of %v", []Mode{Summarize, Synchronize, Publish, ValidateConfig, Doctor, Lag, CherryPickOne, RewriteGoMod})
 	}
 
 	switch FetchMode(o.FetchMode) {
@@ -109,12 +230,93 @@ func (o *Options) Validate() error {
 	default:
 		return fmt.Errorf("--fetch-mode must be one of %v", []FetchMode{HTTPS, SSH, FILE})
 	}
+	if o.GoBin != "" {
+		if _, err := exec.LookPath(o.GoBin); err != nil {
+			return fmt.Errorf("--go-bin: %w", err)
+		}
+	}
+
+	if o.IssueRef != DefaultIssueRef && !issueRefRegex.MatchString(o.IssueRef) {
+		return fmt.Errorf("--issue-ref must be %s or match %s, got %q", DefaultIssueRef, issueRefRegex, o.IssueRef)
+	}
+
+	if o.JiraURL != "" {
+		if o.JiraTokenPath == "" || o.JiraIssue == "" {
+			return fmt.Errorf("--jira-url requires --jira-token-path and --jira-issue")
+		}
+		if !issueRefRegex.MatchString(o.JiraIssue) {
+			return fmt.Errorf("--jira-issue must match %s, got %q", issueRefRegex, o.JiraIssue)
+		}
+		if _, err := os.Stat(o.JiraTokenPath); err != nil {
+			return fmt.Errorf("--jira-token-path: %w", err)
+		}
+	} else if o.JiraIssue != "" || o.JiraTransition != "" {
+		return fmt.Errorf("--jira-issue and --jira-transition require --jira-url")
+	}
+
+	if o.RequireSignedCarries && !o.RequireSignedUpstream {
+		return fmt.Errorf("--require-signed-carries requires --require-signed-upstream")
+	}
+	// the commands verifying signatures run in the repositories, so relative paths would not resolve
+	for flag, path := range map[string]*string{"allowed-signers": &o.AllowedSigners, "gpg-home": &o.GPGHome} {
+		if *path == "" {
+			continue
+		}
+		if _, err := os.Stat(*path); err != nil {
+			return fmt.Errorf("--%s: %w", flag, err)
+		}
+		absPath, err := filepath.Abs(*path)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", flag, err)
+		}
+		*path = absPath
+	}
+
+	if _, err := o.ParseBodyTemplate(); err != nil {
+		return fmt.Errorf("--body-template: %w", err)
+	}
+
+	if o.MaxFileSize < 0 {
+		return fmt.Errorf("--max-file-size must not be negative")
+	}
+	if o.WarnLargeFiles && o.MaxFileSize == 0 {
+		return fmt.Errorf("--warn-large-files requires --max-file-size")
+	}
+
+	if o.GoModRetries < 0 {
+		return fmt.Errorf("--gomod-retries must not be negative")
+	}
+
+	if o.Deadline < 0 {
+		return fmt.Errorf("--deadline must not be negative")
+	}
+	for _, entry := range o.GitConfig.Strings() {
+		if key, _, ok := strings.Cut(entry, "="); !ok || key == "" {
+			return fmt.Errorf("--git-config must be in the form key=value, got %q", entry)
+		}
+	}
+	if o.AddCoauthor && (o.GitName == "" || o.GitEmail == "") {
+		return fmt.Errorf("--add-coauthor requires --git-name and --git-email")
+	}
+
+	if FetchMode(o.FetchMode) == FILE && o.FetchDir == "" {
+		return fmt.Errorf("--fetch-dir is required for --fetch-mode=%s", FILE)
+	}
+
+	if o.FetchDepth < 0 {
+		return fmt.Errorf("--fetch-depth must not be negative")
+	}
+
+	if o.DetectOnly && o.CommitFileOutput == "" {
+		return fmt.Errorf("--detect-only requires --commits-output")
+	}
 
 	if _, err := logrus.ParseLevel(o.LogLevel); err != nil {
 		return fmt.Errorf("--log-level invalid: %w", err)
 	}
 
-	if Mode(o.Mode) == Publish {
+	// rewriting go.mod publishes its own pull request, so it needs the same options
+	if Mode(o.Mode) == Publish || Mode(o.Mode) == RewriteGoMod {
 		if o.GithubLogin == "" {
 			return fmt.Errorf("--github-login is mandatory")
 		}
@@ -125,9 +327,20 @@ func (o *Options) Validate() error {
 			return fmt.Errorf("--assign is mandatory")
 		}
 
+		if !o.DryRun && o.GitHubOptions.TokenPath == "" && o.GitHubOptions.AppID == "" {
+			return fmt.Errorf("--github-token-path is mandatory to publish")
+		}
+
 		if err := o.GitHubOptions.Validate(o.DryRun); err != nil {
 			return err
 		}
+	} else if o.GithubReadTokenPath != "" {
+		if _, err := os.Stat(o.GithubReadTokenPath); err != nil {
+			return fmt.Errorf("--github-read-token-path: %w", err)
+		}
+		if err := o.GitHubOptions.Validate(true); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -139,3 +352,136 @@ func (o *Options) GitCommitArgs() []string {
 	}
 	return commitArgs
 }
+
+// GitGeneratedCommitArgs are the arguments for commits that are generated by this tool, rather than carried.
+func (o *Options) GitGeneratedCommitArgs() []string {
+	commitArgs := o.GitCommitArgs()
+	if o.GeneratedBy {
+		commitArgs = append(commitArgs, "--trailer", "Generated-by: operator-framework-tooling@"+Version)
+	}
+	return commitArgs
+}
+
+// GitCarryCommitArgs are the arguments for commits that carry an upstream or downstream commit.
+// Callers should set trailer.ifexists=addIfDifferent so that re-carried commits do not duplicate trailers.
+func (o *Options) GitCarryCommitArgs() []string {
+	commitArgs := o.GitCommitArgs()
+	if o.AddCoauthor {
+		commitArgs = append(commitArgs, "--trailer", fmt.Sprintf("Co-authored-by: %s <%s>", o.GitName, o.GitEmail))
+	}
+	return commitArgs
+}
+
+func (o *Options) GitFetchArgs() []string {
+	var fetchArgs []string
+	if o.FetchDepth > 0 {
+		fetchArgs = append(fetchArgs, "--depth", strconv.Itoa(o.FetchDepth))
+	}
+	return fetchArgs
+}
+
+// Mutates reports whether the mode modifies the repositories, and so must hold their locks.
+func (o *Options) Mutates() bool {
+	switch Mode(o.Mode) {
+	case Synchronize, Publish, RewriteGoMod, CherryPickOne:
+		return true
+	}
+	return false
+}
+
+// PathSpec returns the arguments that limit git log to the commits touching the filtered paths, if any. They must
+// come last.
+func (o *Options) PathSpec() []string {
+	if len(o.PathFilters.Strings()) == 0 {
+		return nil
+	}
+	return append([]string{"--"}, o.PathFilters.Strings()...)
+}
+
+// PinCommitterDate sets the committer date of the commits created from now on with GitEnv to date.
+func (o *Options) PinCommitterDate(date time.Time) {
+	o.committerDate = date
+}
+
+// GitEnv returns the environment of the git commands that create commits, pinning their committer date once
+// PinCommitterDate was called.
+func (o *Options) GitEnv() []string {
+	env := os.Environ()
+	if !o.committerDate.IsZero() {
+		env = append(env, "GIT_COMMITTER_DATE="+o.committerDate.Format(time.RFC3339))
+	}
+	return env
+}
+
+func (o *Options) GoEnv() []string {
+	env := os.Environ()
+	if o.GoProxy != "" {
+		env = append(env, "GOPROXY="+o.GoProxy)
+	}
+	if o.GoFlags != "" {
+		env = append(env, "GOFLAGS="+o.GoFlags)
+	}
+	if o.GoBin != "" {
+		env = append(env, "GOTOOLCHAIN=local")
+	}
+	return env
+}
+
+// Go determines the go binary to use for go mod operations.
+func (o *Options) Go() string {
+	if o.GoBin != "" {
+		return o.GoBin
+	}
+	return "go"
+}
+
+// PRTitle is the title of the synchronization pull request.
+func (o *Options) PRTitle() string {
+	return o.IssueRef + ": Synchronize From Upstream Repositories"
+}
+
+// ParseBodyTemplate parses the pull request body template, returning nil if none was specified.
+func (o *Options) ParseBodyTemplate() (*template.Template, error) {
+	if o.BodyTemplate == "" {
+		return nil, nil
+	}
+	return template.ParseFiles(o.BodyTemplate)
+}
+
+// JiraClient creates a client for the Jira issue to report to, returning nil if none was specified.
+func (o *Options) JiraClient() (*jira.Client, error) {
+	if o.JiraURL == "" {
+		return nil, nil
+	}
+	return jira.NewClient(o.JiraURL, o.JiraTokenPath, o.JiraIssue)
+}
+
+// GetDiffStat computes the changes made by the synchronization in dir since base for the pull request body, returning
+// nil unless --include-diffstat was given. Failures are logged, as the diff stat is informational.
+func (o *Options) GetDiffStat(ctx context.Context, logger *logrus.Entry, dir, base string) *internal.DiffStat {
+	if !o.DiffStat {
+		return nil
+	}
+	stat, err := internal.GetDiffStat(ctx, logger, dir, base)
+	if err != nil {
+		logger.WithError(err).Warn("failed to determine diff stat")
+	}
+	return stat
+}
+
+// SignatureVerifier creates a verifier for commit signatures with the configured trust store.
+func (o *Options) SignatureVerifier() internal.SignatureVerifier {
+	return internal.SignatureVerifier{AllowedSigners: o.AllowedSigners, GPGHome: o.GPGHome}
+}
+
+// ReadGitHubClient creates a GitHub client for API reads. The read-only token is used if one was given, otherwise the
+// client falls back to the write-scoped token, or to anonymous access, in dry-run mode.
+func (o *Options) ReadGitHubClient() (github.Client, error) {
+	if o.GithubReadTokenPath == "" {
+		return o.GitHubOptions.GitHubClient(true)
+	}
+	if err := secret.Add(o.GithubReadTokenPath); err != nil {
+		return nil, fmt.Errorf("failed to add read-only GitHub token to secret agent: %w", err)
+	}
+	return o.GitHubOptions.GitHubClientWithAccessToken(string(secret.GetTokenGenerator(o.GithubReadTokenPath)()))
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// WriteGitHubOutputs appends outputs to the GitHub Actions step output file at path, as key=value lines.
+func WriteGitHubOutputs(path string, outputs map[string]string) error {
+	var keys []string
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var lines []string
+	for _, key := range keys {
+		lines = append(lines, key+"="+outputs[key])
+	}
+
+	out, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("could not open GitHub output file: %w", err)
+	}
+	if _, err := out.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		out.Close()
+		return fmt.Errorf("could not write GitHub outputs: %w", err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/github"
+)
+
+// AuditUpstreamPRs looks up the pull requests in the upstream org that the commits reference with `UPSTREAM: 1234:`, and fails
+// if any of them was closed without merging: those changes will never land upstream, so the commits should be
+// re-classified as `<carry>` instead.
+func AuditUpstreamPRs(logger *logrus.Entry, gc github.Client, org string, commits []Commit) error {
+	unmerged := map[string]bool{}
+	var offending []string
+	for _, commit := range commits {
+		repo, number := UpstreamPR(org, commit)
+		if number == "" {
+			continue
+		}
+		ref := repo + "#" + number
+		closed, seen := unmerged[ref]
+		if !seen {
+			org, name, ok := strings.Cut(repo, "/")
+			if !ok {
+				return fmt.Errorf("commit %s references pull request %s in an invalid repository", commit.Hash, ref)
+			}
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return fmt.Errorf("commit %s references invalid pull request %s: %w", commit.Hash, ref, err)
+			}
+			pr, err := gc.GetPullRequest(org, name, n)
+			if err != nil {
+				return fmt.Errorf("failed to look up pull request %s: %w", ref, err)
+			}
+			closed = pr.State == github.PullRequestStateClosed && !pr.Merged
+			unmerged[ref] = closed
+		}
+		if closed {
+			logger.WithFields(logrus.Fields{"commit": commit.Hash, "pr": ref}).Warn("commit references an upstream pull request that was closed without merging, it should be a <carry>")
+			offending = append(offending, fmt.Sprintf("%s (%s)", commit.Hash, ref))
+		}
+	}
+	if len(offending) > 0 {
+		return fmt.Errorf("commits reference upstream pull requests that were closed without merging, re-classify them as <carry>: %s", strings.Join(offending, ", "))
+	}
+	return nil
+}
@@ -4,7 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -50,13 +55,60 @@ func SetCommitter(ctx context.Context, logger *logrus.Entry, name string, email
 	return nil
 }
 
+// SetLocalCommitter sets the committer identity in the local git config of the repository in dir, so that it only
+// applies there. Empty values are left unset.
+func SetLocalCommitter(ctx context.Context, logger *logrus.Entry, dir, name, email string) error {
+	for _, field := range []struct{ key, value string }{
+		{key: "user.name", value: name},
+		{key: "user.email", value: email},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "config", "--local", field.key, field.value,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyGitConfig sets each of the key=value entries in the local git config of the repository in dir.
+func ApplyGitConfig(ctx context.Context, logger *logrus.Entry, dir string, entries []string) error {
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "=")
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "config", "--local", key, value,
+		), dir)); err != nil {
+			return fmt.Errorf("failed to set git config %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
 func RunCommand(logger *logrus.Entry, cmd *exec.Cmd) (string, error) {
 	output := bytes.Buffer{}
 	cmd.Stdout = bumper.HideSecretsWriter{Delegate: &output, Censor: secret.Censor}
 	cmd.Stderr = bumper.HideSecretsWriter{Delegate: &output, Censor: secret.Censor}
+	defer FlushLog(logger)
 	logger = logger.WithFields(logrus.Fields{"command": cmd.String(), "dir": cmd.Dir})
 	logger.Debug("running command")
-	if err := cmd.Run(); err != nil {
+	if err := recordCommand(cmd); err != nil {
+		logger.WithError(err).Warn("failed to record command in script")
+	}
+	// the span is named after the subcommand, e.g. git fetch, so that the spans of a phase can be told apart
+	name := filepath.Base(cmd.Path)
+	if len(cmd.Args) > 1 {
+		name += " " + cmd.Args[1]
+	}
+	span := StartPhase(logger, name, map[string]string{"command": cmd.String(), "dir": cmd.Dir})
+	start := time.Now()
+	err := cmd.Run()
+	span.SetAttribute("duration", time.Since(start).String())
+	span.SetAttribute("exit", strconv.Itoa(cmd.ProcessState.ExitCode()))
+	span.End(err)
+	if err != nil {
 		return output.String(), fmt.Errorf("failed to run command: %s: %w", output.String(), err)
 	}
 	logger.WithField("output", output.String()).Debug("ran command")
@@ -72,3 +124,140 @@ func WithDir(command *exec.Cmd, dir string) *exec.Cmd {
 	command.Dir = dir
 	return command
 }
+
+// DefaultBranch determines the default branch of the origin remote in dir, as recorded in refs/remotes/origin/HEAD.
+func DefaultBranch(ctx context.Context, logger *logrus.Entry, dir string) (string, error) {
+	output, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD",
+	), dir))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(output), "origin/"), nil
+}
+
+// RefExists determines if ref resolves to a commit in dir.
+func RefExists(ctx context.Context, logger *logrus.Entry, dir, ref string) bool {
+	_, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", "--verify", "--quiet", ref+"^{commit}",
+	), dir))
+	return err == nil
+}
+
+// Unshallow fetches the complete history for ref from remote, if the repository in dir is shallow.
+// It returns whether any history was fetched.
+func Unshallow(ctx context.Context, logger *logrus.Entry, dir, remote, ref string) (bool, error) {
+	output, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", "--is-shallow-repository",
+	), dir))
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(output) != "true" {
+		return false, nil
+	}
+	logger.Info("repository is shallow, fetching complete history")
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "fetch", "--unshallow", remote, ref,
+	), dir)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GoModCommands returns the commands that tidy, optionally vendor, and verify the module in dir using the goBin binary.
+func GoModCommands(ctx context.Context, goBin, dir string, env []string, vendor bool) []*exec.Cmd {
+	subcommands := []string{"tidy", "vendor", "verify"}
+	if !vendor {
+		subcommands = []string{"tidy", "verify"}
+	}
+	var commands []*exec.Cmd
+	for _, subcommand := range subcommands {
+		commands = append(commands, WithEnv(WithDir(exec.CommandContext(ctx,
+			goBin, "mod", subcommand,
+		), dir), env...))
+	}
+	return commands
+}
+
+// transientGoModRegex matches the output of go mod commands that failed to download modules for reasons that may
+// not recur, as opposed to problems with the modules themselves.
+var transientGoModRegex = regexp.MustCompile(`(?i)(dial tcp|i/o timeout|connection reset|connection refused|TLS handshake timeout|unexpected EOF|502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout|429 Too Many Requests)`)
+
+// RunGoMod tidies, optionally vendors, and verifies the module in dir. If the sequence fails to download modules, it
+// is retried up to retries times with a backoff; other failures are returned immediately.
+func RunGoMod(ctx context.Context, logger *logrus.Entry, goBin, dir string, env []string, vendor bool, retries int) error {
+	span := StartPhase(logger, "go mod", map[string]string{"dir": dir})
+	err := runGoMod(ctx, logger, goBin, dir, env, vendor, retries)
+	span.End(err)
+	return err
+}
+
+func runGoMod(ctx context.Context, logger *logrus.Entry, goBin, dir string, env []string, vendor bool, retries int) error {
+	backoff := 10 * time.Second
+	for attempt := 0; ; attempt++ {
+		var output string
+		var err error
+		for _, cmd := range GoModCommands(ctx, goBin, dir, env, vendor) {
+			if output, err = RunCommand(logger, cmd); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if !transientGoModRegex.MatchString(output) {
+			return err
+		}
+		if attempt >= retries {
+			return WithExitCode(ExitTransient, err)
+		}
+		logger.WithError(err).WithField("attempt", attempt+1).Warnf("go mod failed to download modules, retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// PresentPaths filters paths, relative to dir, to those that exist on disk or are tracked by git. Passing other paths
+// to `git add` or `git commit` fails, which happens when e.g. `go mod vendor` does not create a vendor directory
+// for a module without dependencies.
+func PresentPaths(ctx context.Context, logger *logrus.Entry, dir string, paths []string) ([]string, error) {
+	var present []string
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+			present = append(present, path)
+			continue
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		// a tracked path that no longer exists is still valid, and staging it records the deletion
+		output, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "ls-files", "--", path,
+		), dir))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(output) != "" {
+			present = append(present, path)
+			continue
+		}
+		logger.WithField("path", path).Debug("path does not exist, skipping")
+	}
+	return present, nil
+}
+
+// LogGoVersion logs the version of the goBin binary that will be used for go mod operations.
+func LogGoVersion(ctx context.Context, logger *logrus.Entry, goBin string, env []string) error {
+	output, err := RunCommand(logger, WithEnv(exec.CommandContext(ctx,
+		goBin, "version",
+	), env...))
+	if err != nil {
+		return fmt.Errorf("failed to determine go version: %w", err)
+	}
+	logger.WithFields(logrus.Fields{"go": goBin, "version": strings.TrimSpace(output)}).Info("using go toolchain")
+	return nil
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal/gittest"
+	"github.com/sirupsen/logrus"
+)
+
+// newModule initializes a repository holding a module without dependencies, isolated from the user's git config.
+func newModule(t *testing.T) string {
+	t.Helper()
+	gittest.Isolate(t)
+	dir := t.TempDir()
+	gittest.Git(t, dir, "init")
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/nodeps\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Git(t, dir, "add", ".")
+	gittest.Git(t, dir, "commit", "--message", "add module")
+	return dir
+}
+
+func TestPresentPaths(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.NewEntry(logrus.New())
+	for _, tc := range []struct {
+		name     string
+		setup    func(t *testing.T, dir string)
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "missing untracked paths are skipped",
+			paths:    []string{"vendor", "go.mod", "go.sum"},
+			expected: []string{"go.mod"},
+		},
+		{
+			name: "deleted tracked paths are kept to record the deletion",
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, "go.sum"), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				gittest.Git(t, dir, "add", "go.sum")
+				gittest.Git(t, dir, "commit", "--message", "add go.sum")
+				if err := os.Remove(filepath.Join(dir, "go.sum")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			paths:    []string{"vendor", "go.mod", "go.sum"},
+			expected: []string{"go.mod", "go.sum"},
+		},
+		{
+			name: "untracked paths that exist are kept",
+			setup: func(t *testing.T, dir string) {
+				if err := os.MkdirAll(filepath.Join(dir, "vendor"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "vendor", "modules.txt"), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			paths:    []string{"vendor", "go.mod", "go.sum"},
+			expected: []string{"vendor", "go.mod"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newModule(t)
+			if tc.setup != nil {
+				tc.setup(t, dir)
+			}
+			present, err := PresentPaths(ctx, logger, dir, tc.paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(present, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, present)
+			}
+		})
+	}
+}
+
+// TestPresentPathsNoDependencies covers the module files being committed after the go mod commands, as applyConfig
+// does, for a module whose vendoring creates neither a vendor directory nor a go.sum.
+func TestPresentPathsNoDependencies(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.NewEntry(logrus.New())
+	dir := newModule(t)
+	if err := RunGoMod(ctx, logger, "go", dir, os.Environ(), true, 0); err != nil {
+		t.Fatalf("go mod commands failed for a module without dependencies: %v", err)
+	}
+
+	paths, err := PresentPaths(ctx, logger, dir, []string{"vendor", "go.mod", "go.sum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(paths, []string{"go.mod"}) {
+		t.Fatalf("expected only go.mod to be present, got %v", paths)
+	}
+	for _, args := range [][]string{
+		append([]string{"add", "--force"}, paths...),
+		append([]string{"commit", "--allow-empty", "--message", "go mod vendor"}, paths...),
+	} {
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx, "git", args...), dir)); err != nil {
+			t.Errorf("expected git %s to accept the present paths: %v", args[0], err)
+		}
+	}
+}
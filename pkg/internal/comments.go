@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/github"
+)
+
+// FindSyncPR finds the open pull request for headBranch created by the bot in org/repo, if any.
+// This matches the search used to decide which pull request to update when publishing.
+func FindSyncPR(gc github.Client, org, repo, headBranch string) (*github.Issue, error) {
+	me, err := gc.BotUser()
+	if err != nil {
+		return nil, fmt.Errorf("bot name: %w", err)
+	}
+	issues, err := gc.FindIssues(fmt.Sprintf("is:open is:pr archived:false repo:%s/%s author:%s head:%s", org, repo, me.Login, headBranch), "updated", false)
+	if err != nil {
+		return nil, fmt.Errorf("find issues: %w", err)
+	}
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	return &issues[0], nil
+}
+
+// PullRequestURL returns the URL to open a pull request against base in org/repo from head, which is either
+// user:branch or user:repo:branch, with the title filled in.
+func PullRequestURL(org, repo, base, head, title string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/compare/%s...%s?expand=1&title=%s", org, repo, base, head, url.QueryEscape(title))
+}
+
+// BodyDelta summarizes the table rows in body that were not present in previousBody.
+// An empty string is returned if there are none.
+func BodyDelta(previousBody, body string) string {
+	previous := map[string]bool{}
+	for _, line := range strings.Split(previousBody, "\n") {
+		previous[line] = true
+	}
+
+	lines := []string{
+		"This pull request has been updated with the following changes since the previous synchronization:",
+		"",
+	}
+	changed := false
+	for _, line := range strings.Split(body, "\n") {
+		// only the table rows describe changes, and the header rows are the same in every body
+		if !strings.HasPrefix(line, "|") || strings.HasPrefix(line, "| ") || previous[line] {
+			continue
+		}
+		// the tables have differing columns, so render the rows as a list instead
+		lines = append(lines, "* "+strings.Join(strings.Split(strings.Trim(line, "|"), "|"), " | "))
+		changed = true
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CommentOnUpdate comments on an existing sync pull request with the delta between its previous body and the new one.
+// Failures are logged but not returned, as the comment is informational.
+func CommentOnUpdate(logger *logrus.Entry, gc github.Client, org, repo string, existing *github.Issue, body string, dryRun bool) {
+	if existing == nil {
+		return
+	}
+	comment := BodyDelta(existing.Body, body)
+	if comment == "" {
+		logger.Info("no changes since the previous synchronization, not commenting")
+		return
+	}
+	if dryRun {
+		logger.WithField("comment", comment).Infof("would comment on %s/%s#%d", org, repo, existing.Number)
+		return
+	}
+	if err := gc.CreateComment(org, repo, existing.Number, comment); err != nil {
+		logger.WithError(err).Warn("failed to comment on updated pull request")
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConflictReport describes a cherry-pick that conflicted, alongside the patch files written for it.
+type ConflictReport struct {
+	Repo       string   `json:"repo"`
+	Commit     Commit   `json:"commit"`
+	Conflicted []string `json:"conflicted"`
+	Patch      string   `json:"patch"`
+	Diff       string   `json:"diff"`
+}
+
+// WriteConflictPatch records the cherry-pick of commit that conflicted in dir, so that it can be resolved offline: the
+// commit's patch, the diff of the conflicted working tree against the index, and a JSON report of both along with the
+// conflicted paths are written to outDir. The cherry-pick is then aborted, leaving dir as it was before it.
+func WriteConflictPatch(ctx context.Context, logger *logrus.Entry, dir, outDir, repo string, commit Commit) error {
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return fmt.Errorf("failed to create conflict patch directory: %w", err)
+	}
+	prefix := strings.ReplaceAll(repo, "/", "-") + "-" + commit.Hash[0:7]
+	if repo == "" {
+		prefix = commit.Hash[0:7]
+	}
+	patch, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "format-patch", "-1", "--stdout", commit.Hash,
+	), dir))
+	if err != nil {
+		return fmt.Errorf("failed to format patch: %w", err)
+	}
+	diff, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff",
+	), dir))
+	if err != nil {
+		return fmt.Errorf("failed to diff conflicts: %w", err)
+	}
+	rawConflicted, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff", "--name-only", "--diff-filter=U",
+	), dir))
+	if err != nil {
+		return fmt.Errorf("failed to list conflicts: %w", err)
+	}
+
+	report := ConflictReport{
+		Repo:       repo,
+		Commit:     commit,
+		Conflicted: strings.Fields(rawConflicted),
+		Patch:      prefix + ".patch",
+		Diff:       prefix + ".diff",
+	}
+	rawReport, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal conflict report: %w", err)
+	}
+	for name, content := range map[string]string{
+		report.Patch:     patch,
+		report.Diff:      diff,
+		prefix + ".json": string(rawReport) + "\n",
+	} {
+		if err := os.WriteFile(filepath.Join(outDir, name), []byte(content), 0666); err != nil {
+			return fmt.Errorf("could not write conflict patch: %w", err)
+		}
+	}
+	logger.WithFields(logrus.Fields{"commit": commit.Hash, "dir": outDir}).Info("wrote conflicting patch")
+
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "cherry-pick", "--abort",
+	), dir)); err != nil {
+		return fmt.Errorf("failed to abort cherry-pick: %w", err)
+	}
+	return nil
+}
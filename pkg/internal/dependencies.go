@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DependencyChange describes how the version of a module changed in go.mod.
+// An empty Old or New means the module was added or removed, respectively.
+type DependencyChange struct {
+	Module string
+	Old    string
+	New    string
+}
+
+type goModFile struct {
+	Require []struct {
+		Path    string
+		Version string
+	}
+	Replace []struct {
+		Old struct {
+			Path    string
+			Version string
+		}
+		New struct {
+			Path    string
+			Version string
+		}
+	}
+}
+
+// GoModChanges compares the go.mod in dir between base and HEAD, returning the require and replace directives
+// that differ. The go.mod files are parsed with goBin and goEnv.
+func GoModChanges(ctx context.Context, logger *logrus.Entry, goBin, dir, base string, goEnv []string) ([]DependencyChange, error) {
+	before, err := goModVersions(ctx, logger, goBin, dir, base, goEnv)
+	if err != nil {
+		return nil, err
+	}
+	after, err := goModVersions(ctx, logger, goBin, dir, "HEAD", goEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []DependencyChange
+	for module, version := range after {
+		if before[module] != version {
+			changes = append(changes, DependencyChange{Module: module, Old: before[module], New: version})
+		}
+	}
+	for module, version := range before {
+		if _, ok := after[module]; !ok {
+			changes = append(changes, DependencyChange{Module: module, Old: version})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Module < changes[j].Module
+	})
+	return changes, nil
+}
+
+// goModVersions reads go.mod at the given ref and returns a mapping of module to version. Replaced
+// modules are keyed with a " =>" suffix so that changes to the replacement show up separately.
+func goModVersions(ctx context.Context, logger *logrus.Entry, goBin, dir, ref string, goEnv []string) (map[string]string, error) {
+	rawGoMod, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "show", ref+":go.mod",
+	), dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.mod at %s: %w", ref, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gomod")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	goModPath := filepath.Join(tmpDir, "go.mod")
+	if err := os.WriteFile(goModPath, []byte(rawGoMod), 0666); err != nil {
+		return nil, err
+	}
+
+	goMod, err := parseGoMod(ctx, logger, goBin, goModPath, goEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod at %s: %w", ref, err)
+	}
+
+	versions := map[string]string{}
+	for _, require := range goMod.Require {
+		versions[require.Path] = require.Version
+	}
+	for _, replace := range goMod.Replace {
+		old := replace.Old.Path
+		if replace.Old.Version != "" {
+			old += "@" + replace.Old.Version
+		}
+		versions[old+" =>"] = strings.TrimSuffix(replace.New.Path+"@"+replace.New.Version, "@")
+	}
+	return versions, nil
+}
+
+func parseGoMod(ctx context.Context, logger *logrus.Entry, goBin, path string, goEnv []string) (*goModFile, error) {
+	rawJson, err := RunCommand(logger, WithEnv(exec.CommandContext(ctx,
+		goBin, "mod", "edit", "-json", path,
+	), goEnv...))
+	if err != nil {
+		return nil, err
+	}
+	var goMod goModFile
+	if err := json.Unmarshal([]byte(rawJson), &goMod); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", path, err)
+	}
+	return &goMod, nil
+}
+
+// ModuleDrift compares the go.mod files in the root and nested module directories, returning the modules that both
+// require at different versions. Old is the root module's version, and New the nested module's.
+func ModuleDrift(ctx context.Context, logger *logrus.Entry, goBin, rootDir, nestedDir string, goEnv []string) ([]DependencyChange, error) {
+	root, err := parseGoMod(ctx, logger, goBin, filepath.Join(rootDir, "go.mod"), goEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse root go.mod: %w", err)
+	}
+	nested, err := parseGoMod(ctx, logger, goBin, filepath.Join(nestedDir, "go.mod"), goEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nested go.mod: %w", err)
+	}
+
+	rootVersions := map[string]string{}
+	for _, require := range root.Require {
+		rootVersions[require.Path] = require.Version
+	}
+	var drift []DependencyChange
+	for _, require := range nested.Require {
+		if version, ok := rootVersions[require.Path]; ok && version != require.Version {
+			drift = append(drift, DependencyChange{Module: require.Path, Old: version, New: require.Version})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].Module < drift[j].Module
+	})
+	return drift, nil
+}
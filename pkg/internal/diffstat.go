@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ShortStat counts the files and lines changed by a diff.
+type ShortStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+func (s ShortStat) String() string {
+	return fmt.Sprintf("%d files changed, +%d/-%d", s.Files, s.Insertions, s.Deletions)
+}
+
+// DiffStat summarizes the changes made by a synchronization. The vendored dependencies are counted apart from the
+// source, as their churn would otherwise drown out the meaningful changes.
+type DiffStat struct {
+	Source ShortStat
+	Vendor ShortStat
+}
+
+// vendorGlob matches the vendor directories at any depth.
+const vendorGlob = "**/vendor/**"
+
+// GetDiffStat computes the changes between base and HEAD in dir.
+func GetDiffStat(ctx context.Context, logger *logrus.Entry, dir, base string) (*DiffStat, error) {
+	source, err := shortStat(ctx, logger, dir, base, ".", ":(exclude,glob)"+vendorGlob)
+	if err != nil {
+		return nil, err
+	}
+	vendor, err := shortStat(ctx, logger, dir, base, ":(glob)"+vendorGlob)
+	if err != nil {
+		return nil, err
+	}
+	return &DiffStat{Source: source, Vendor: vendor}, nil
+}
+
+var shortStatRegex = regexp.MustCompile(`(\d+) (files? changed|insertions?\(\+\)|deletions?\(-\))`)
+
+func shortStat(ctx context.Context, logger *logrus.Entry, dir, base string, pathspecs ...string) (ShortStat, error) {
+	output, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"diff", "--shortstat", base + "..HEAD", "--"}, pathspecs...)...,
+	), dir))
+	if err != nil {
+		return ShortStat{}, fmt.Errorf("failed to compute diff stat: %w", err)
+	}
+	// e.g. ` 3 files changed, 10 insertions(+), 2 deletions(-)`, where the counts that are zero are left out
+	var stat ShortStat
+	for _, match := range shortStatRegex.FindAllStringSubmatch(output, -1) {
+		count, err := strconv.Atoi(match[1])
+		if err != nil {
+			return ShortStat{}, err
+		}
+		switch match[2][0] {
+		case 'f':
+			stat.Files = count
+		case 'i':
+			stat.Insertions = count
+		case 'd':
+			stat.Deletions = count
+		}
+	}
+	return stat, nil
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DownstreamIgnoreFile lists the paths of a repo that are owned downstream, such as OWNERS files or downstream
+// Dockerfiles, with gitignore syntax. Upstream commits must not change them.
+const DownstreamIgnoreFile = ".downstream-ignore"
+
+// DownstreamOwned filters paths, which must be in the index of the repo in dir, to the ones listed in its
+// DownstreamIgnoreFile. Without the file, no path is owned downstream.
+func DownstreamOwned(ctx context.Context, logger *logrus.Entry, dir string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, DownstreamIgnoreFile)); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	rawOwned, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"ls-files", "--cached", "--ignored", "--exclude-from=" + DownstreamIgnoreFile, "--"}, paths...)...,
+	), dir))
+	if err != nil {
+		return nil, err
+	}
+	var owned []string
+	for _, path := range strings.Split(strings.TrimSpace(rawOwned), "\n") {
+		// conflicted paths are listed once for each stage
+		if path != "" && !slices.Contains(owned, path) {
+			owned = append(owned, path)
+		}
+	}
+	return owned, nil
+}
+
+// RestoreDownstreamOwned reverts the changes that the commit at HEAD in dir made to the paths owned downstream, and
+// amends it.
+func RestoreDownstreamOwned(ctx context.Context, logger *logrus.Entry, dir string, env []string) error {
+	rawChanged, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff-tree", "-r", "--no-commit-id", "--name-only", "HEAD",
+	), dir))
+	if err != nil {
+		return err
+	}
+	owned, err := DownstreamOwned(ctx, logger, dir, strings.Fields(rawChanged))
+	if err != nil {
+		return err
+	}
+	if len(owned) == 0 {
+		return nil
+	}
+	for _, path := range owned {
+		logger.WithField("path", path).Info("restoring downstream version of path listed in " + DownstreamIgnoreFile)
+		restore := []string{"checkout", "HEAD^", "--", path}
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "cat-file", "-e", "HEAD^:"+path,
+		), dir)); err != nil {
+			// the path was added by the commit
+			restore = []string{"rm", "--quiet", "--", path}
+		}
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", restore...,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	_, err = RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		"git", "commit", "--amend", "--allow-empty", "--no-edit",
+	), dir), env...))
+	return err
+}
+
+// DownstreamOwnedFile lists the paths of a repo that only exist downstream, such as the openshift/ build files, with
+// gitignore syntax. Unlike carried commits, they are kept as they are on the downstream branch across every
+// synchronization, even where upstream introduced files of the same name.
+const DownstreamOwnedFile = ".downstream-owned"
+
+// RestoreDownstreamFiles re-materializes the paths listed in the DownstreamOwnedFile at ref in the repo in dir, as
+// they are at ref, and commits them on top of HEAD with the message. Without the file at ref, nothing is restored.
+func RestoreDownstreamFiles(ctx context.Context, logger *logrus.Entry, dir, ref, message string, commitArgs, env []string) error {
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "cat-file", "-e", ref+":"+DownstreamOwnedFile,
+	), dir)); err != nil {
+		logger.Debugf("no %s, not restoring any downstream files", DownstreamOwnedFile)
+		return nil
+	}
+	manifest, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "show", ref+":"+DownstreamOwnedFile,
+	), dir))
+	if err != nil {
+		return err
+	}
+	scratch, err := os.MkdirTemp("", "downstream-owned")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(scratch); err != nil {
+			logger.WithError(err).Warn("failed to remove scratch directory")
+		}
+	}()
+	manifestPath := filepath.Join(scratch, DownstreamOwnedFile)
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0o644); err != nil {
+		return err
+	}
+
+	// the manifest is matched against the paths at ref, so they are listed from a scratch index of its tree
+	scratchEnv := append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(scratch, "index"))
+	if _, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		"git", "read-tree", ref,
+	), dir), scratchEnv...)); err != nil {
+		return err
+	}
+	rawOwned, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		"git", "ls-files", "--cached", "--ignored", "--exclude-from="+manifestPath,
+	), dir), scratchEnv...))
+	if err != nil {
+		return err
+	}
+	owned := []string{DownstreamOwnedFile}
+	for _, path := range strings.Split(strings.TrimSpace(rawOwned), "\n") {
+		if path != "" && !slices.Contains(owned, path) {
+			owned = append(owned, path)
+		}
+	}
+	for _, path := range owned {
+		logger.WithField("path", path).Info("re-materializing downstream version of path listed in " + DownstreamOwnedFile)
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "checkout", ref, "--", path,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	if _, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"commit", "--message", message}, commitArgs...)...,
+	), dir), env...)); err != nil {
+		if strings.Contains(err.Error(), "nothing to commit") {
+			logger.Info("downstream files are already up-to-date, continuing")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"errors"
+	"regexp"
+)
+
+// ExitCode is the exit code of a run, so that the jobs running it can tell why it failed: e.g. only retrying
+// transient failures, and paging on conflicts.
+type ExitCode int
+
+const (
+	// ExitSuccess is returned when the run succeeded, including when there was nothing to do.
+	ExitSuccess ExitCode = 0
+	// ExitFailure is returned for failures that are not classified otherwise.
+	ExitFailure ExitCode = 1
+	// ExitConfig is returned when the options or configuration are invalid.
+	ExitConfig ExitCode = 2
+	// ExitConflict is returned when a commit failed to cherry-pick or merge, which needs a human to resolve.
+	ExitConflict ExitCode = 3
+	// ExitTransient is returned for failures that may not recur when retried, such as network errors.
+	ExitTransient ExitCode = 4
+	// ExitDivergence is returned when the upstream and downstream histories no longer line up as expected.
+	ExitDivergence ExitCode = 5
+)
+
+// ExitError classifies an error with the exit code to exit with.
+type ExitError struct {
+	Code ExitCode
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// WithExitCode classifies err with the exit code, unless it is nil or already classified.
+func WithExitCode(code ExitCode, err error) error {
+	var exitErr *ExitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCodeOf determines the exit code for the error a run returned.
+func ExitCodeOf(err error) ExitCode {
+	if err == nil {
+		return ExitSuccess
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitFailure
+}
+
+// transientGitRegex matches the output of git commands that failed to reach the remote for reasons that may not
+// recur, as opposed to problems with the repository or refs.
+var transientGitRegex = regexp.MustCompile(`(?i)(could not resolve host|connection timed out|connection reset|connection refused|early EOF|RPC failed|unexpected disconnect|the remote end hung up|HTTP 5[0-9][0-9]|returned error: 5[0-9][0-9]|returned error: 429|TLS handshake timeout)`)
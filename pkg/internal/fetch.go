@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fetcher fetches refs from remotes into local repositories. Each fetched ref is stored under refs/fetched/, as
+// FETCH_HEAD does not identify the individual refs once more than one is fetched at a time.
+//
+// When batching, the refs registered up front with Add are fetched along with the first ref fetched from the same
+// remote into the same repository, using a single `git fetch`. Refs that were already fetched, and commits that
+// are already present locally, are not fetched again. Refs that were not known up front are fetched when needed.
+//
+// Refs that do not need their history are fetched with FetchShallow, on their own so that the refs batched with
+// them keep their full history.
+type Fetcher struct {
+	batch   bool
+	args    []string
+	shallow []string
+	pending map[fetchKey][]string
+	fetched map[fetchKey]map[string]bool
+	allTags map[fetchKey]bool
+}
+
+type fetchKey struct {
+	dir    string
+	remote string
+}
+
+// NewFetcher creates a Fetcher that passes args to every `git fetch`, and shallow to those made by FetchShallow.
+func NewFetcher(batch bool, shallow []string, args ...string) *Fetcher {
+	return &Fetcher{
+		batch:   batch,
+		args:    args,
+		shallow: shallow,
+		pending: map[fetchKey][]string{},
+		fetched: map[fetchKey]map[string]bool{},
+		allTags: map[fetchKey]bool{},
+	}
+}
+
+// Add registers refs that will be needed from remote in the repository in dir. This is a no-op when not batching.
+func (f *Fetcher) Add(dir, remote string, refs ...string) {
+	if !f.batch {
+		return
+	}
+	key := fetchKey{dir: dir, remote: remote}
+	f.pending[key] = append(f.pending[key], refs...)
+}
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Fetch ensures ref has been fetched from remote into the repository in dir, returning a local name for it.
+func (f *Fetcher) Fetch(ctx context.Context, logger *logrus.Entry, dir, remote, ref string) (string, error) {
+	span := StartPhase(logger, "fetch", map[string]string{"dir": dir, "remote": remote, "ref": ref})
+	local, err := f.fetch(ctx, logger, dir, remote, ref, false)
+	span.End(err)
+	return local, err
+}
+
+// FetchShallow is Fetch for a ref whose history is not needed, which is fetched with the shallow arguments.
+func (f *Fetcher) FetchShallow(ctx context.Context, logger *logrus.Entry, dir, remote, ref string) (string, error) {
+	span := StartPhase(logger, "fetch", map[string]string{"dir": dir, "remote": remote, "ref": ref, "shallow": "true"})
+	local, err := f.fetch(ctx, logger, dir, remote, ref, len(f.shallow) > 0)
+	span.End(err)
+	return local, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, logger *logrus.Entry, dir, remote, ref string, shallow bool) (string, error) {
+	key := fetchKey{dir: dir, remote: remote}
+	logger = logger.WithFields(logrus.Fields{"remote": remote, "ref": ref})
+	if f.batch {
+		if f.fetched[key][ref] {
+			logger.Debug("ref was already fetched")
+			return f.localRef(remote, ref), nil
+		}
+		if shaRegex.MatchString(ref) {
+			if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+				"git", "cat-file", "-e", ref+"^{commit}",
+			), dir)); err == nil {
+				logger.Debug("commit is already present")
+				return ref, nil
+			}
+		}
+	}
+
+	refs := []string{ref}
+	args := f.args
+	if shallow {
+		args = append(append([]string{}, f.args...), f.shallow...)
+	} else {
+		for _, pending := range f.pending[key] {
+			if pending != ref && !f.fetched[key][pending] {
+				refs = append(refs, pending)
+			}
+		}
+		delete(f.pending, key)
+	}
+
+	var refspecs []string
+	for _, r := range refs {
+		refspecs = append(refspecs, "+"+r+":"+f.localRef(remote, r))
+	}
+	if output, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", append(append(append([]string{"fetch"}, args...), remote), refspecs...)...,
+	), dir)); err != nil {
+		if transientGitRegex.MatchString(output) {
+			return "", WithExitCode(ExitTransient, err)
+		}
+		return "", err
+	}
+
+	if shallow {
+		// not recorded as fetched, so that a Fetch of the ref needing its history is not skipped
+		return f.localRef(remote, ref), nil
+	}
+	if f.fetched[key] == nil {
+		f.fetched[key] = map[string]bool{}
+	}
+	for _, r := range refs {
+		f.fetched[key][r] = true
+	}
+	return f.localRef(remote, ref), nil
+}
+
+// FetchTags fetches the given tags from remote into the repository in dir, or all of its tags if none are given, for
+// when the Fetcher does not fetch tags along with every ref. Once all tags were fetched, they are not fetched again.
+func (f *Fetcher) FetchTags(ctx context.Context, logger *logrus.Entry, dir, remote string, tags ...string) error {
+	key := fetchKey{dir: dir, remote: remote}
+	if f.allTags[key] {
+		return nil
+	}
+	refspecs := []string{"+refs/tags/*:refs/tags/*"}
+	if len(tags) > 0 {
+		refspecs = nil
+		for _, tag := range tags {
+			refspecs = append(refspecs, "+refs/tags/"+tag+":refs/tags/"+tag)
+		}
+	}
+	if _, err := RunCommand(logger.WithField("remote", remote), WithDir(exec.CommandContext(ctx,
+		"git", append(append(append([]string{"fetch"}, f.args...), remote), refspecs...)...,
+	), dir)); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		f.allTags[key] = true
+	}
+	return nil
+}
+
+// localRef determines where ref from remote is stored locally. The remote is hashed, as it may be a URL or a path.
+func (f *Fetcher) localRef(remote, ref string) string {
+	sum := sha256.Sum256([]byte(remote))
+	return fmt.Sprintf("refs/fetched/%x/%s", sum[:8], ref)
+}
+
+// Resolve determines the commit that ref from remote was at when it was last fetched, without fetching it again.
+func (f *Fetcher) Resolve(ctx context.Context, logger *logrus.Entry, dir, remote, ref string) (string, error) {
+	commitSha, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", f.localRef(remote, ref)+"^{commit}",
+	), dir))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s from %s: %w", ref, remote, err)
+	}
+	return strings.TrimSpace(commitSha), nil
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal/gittest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFetcherScopesDepth(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.NewEntry(logrus.New())
+	remote := newModule(t)
+	gittest.Git(t, remote, "commit", "--allow-empty", "--message", "second")
+	gittest.Git(t, remote, "commit", "--allow-empty", "--message", "third")
+	gittest.Git(t, remote, "branch", "pending")
+	fetcher := NewFetcher(true, []string{"--depth", "1"}, "--no-tags")
+
+	shallow := t.TempDir()
+	gittest.Git(t, shallow, "init")
+	fetcher.Add(shallow, remote, "pending")
+	head, err := fetcher.FetchShallow(ctx, logger, shallow, remote, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := gittest.Git(t, shallow, "rev-list", "--count", head); count != "1" {
+		t.Errorf("expected a shallow fetch to fetch one commit, got %s", count)
+	}
+	if _, err := RunCommand(logger, WithDir(exec.Command("git", "rev-parse", "--verify", fetcher.localRef(remote, "pending")), shallow)); err == nil {
+		t.Error("expected the pending ref not to be fetched along with the shallow fetch")
+	}
+
+	full := t.TempDir()
+	gittest.Git(t, full, "init")
+	head, err = fetcher.Fetch(ctx, logger, full, remote, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := gittest.Git(t, full, "rev-list", "--count", head); count != "3" {
+		t.Errorf("expected a fetch to fetch the full history, got %s commits", count)
+	}
+	if shallow := gittest.Git(t, full, "rev-parse", "--is-shallow-repository"); shallow != "false" {
+		t.Error("expected a fetch not to make the repository shallow")
+	}
+}
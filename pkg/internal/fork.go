@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/cmd/generic-autobumper/bumper"
+)
+
+// PushForkBranch pushes HEAD to branch in the fork at remote. By default, the branch is overwritten like
+// bumper.MinimalGitPush does; with noForce, pushing fails if the branch has diverged. With reset, the branch is first
+// updated to base, only if it still points where it did when fetched so that a push made to it in the meantime is not
+// lost, and HEAD is then pushed onto it as a fast-forward. The remote holds credentials, so the push logs to the
+// censored stdout and stderr.
+func PushForkBranch(ctx context.Context, logger *logrus.Entry, dir, remote, branch, base string, reset, noForce bool, stdout, stderr io.Writer, dryRun bool) error {
+	if !reset && !noForce {
+		if !dryRun {
+			// bumper does not run its commands through RunCommand, so the push it makes is recorded on its behalf
+			recordGit(logger, dir, "push", "--force", remote, "HEAD:"+branch)
+		}
+		return bumper.MinimalGitPush(remote, branch, stdout, stderr, dryRun, bumper.WithContext(ctx), bumper.WithDir(dir))
+	}
+
+	// the current commit of the branch is what the reset expects, which is empty when the branch does not exist yet
+	var current string
+	fetchStderr := &bytes.Buffer{}
+	if err := callGit(ctx, logger, dir, stdout, fetchStderr, "fetch", remote, "refs/heads/"+branch); err != nil {
+		if transientGitRegex.MatchString(fetchStderr.String()) {
+			return WithExitCode(ExitTransient, fmt.Errorf("failed to fetch fork branch %s: %w", branch, err))
+		}
+		if !strings.Contains(strings.ToLower(fetchStderr.String()), "couldn't find remote ref") {
+			return fmt.Errorf("failed to fetch fork branch %s: %w", branch, err)
+		}
+	} else {
+		raw, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "rev-parse", "FETCH_HEAD",
+		), dir))
+		if err != nil {
+			return err
+		}
+		current = strings.TrimSpace(raw)
+	}
+
+	// a push that does not change the tree only re-triggers the tests and removes the labels of the pull request
+	if current != "" {
+		if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "diff", "--quiet", current, "HEAD",
+		), dir)); err == nil {
+			logger.WithField("branch", branch).Info("fork branch is already up-to-date, not pushing")
+			return nil
+		}
+	}
+
+	logger = logger.WithField("branch", branch)
+	if reset {
+		commit, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "rev-parse", base+"^{commit}",
+		), dir))
+		if err != nil {
+			return err
+		}
+		commit = strings.TrimSpace(commit)
+		resetLogger := logger.WithFields(logrus.Fields{"base": commit, "lease": current})
+		if dryRun {
+			resetLogger.Info("would reset fork branch to the base")
+		} else {
+			resetLogger.Info("resetting fork branch to the base")
+			if err := callGit(ctx, logger, dir, stdout, stderr,
+				"push", fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branch, current), remote, commit+":refs/heads/"+branch,
+			); err != nil {
+				return fmt.Errorf("failed to reset fork branch %s: %w", branch, err)
+			}
+		}
+	}
+
+	if dryRun {
+		logger.Info("would push to fork branch")
+		return nil
+	}
+	logger.Info("pushing to fork branch")
+	return callGit(ctx, logger, dir, stdout, stderr, "push", remote, "HEAD:refs/heads/"+branch)
+}
+
+// callGit runs git with bumper.Call, which logs to the censored stdout and stderr, for the commands whose arguments
+// hold credentials, recording it in the script like RunCommand does.
+func callGit(ctx context.Context, logger *logrus.Entry, dir string, stdout, stderr io.Writer, args ...string) error {
+	recordGit(logger, dir, args...)
+	return bumper.Call(stdout, stderr, "git", args, bumper.WithContext(ctx), bumper.WithDir(dir))
+}
+
+func recordGit(logger *logrus.Entry, dir string, args ...string) {
+	if err := recordCommand(WithDir(exec.Command("git", args...), dir)); err != nil {
+		logger.WithError(err).Warn("failed to record command in script")
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal/gittest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPushForkBranchResetWithoutForcePush(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.NewEntry(logrus.New())
+	dir := newModule(t)
+	base := gittest.Git(t, dir, "rev-parse", "HEAD")
+	remote := t.TempDir()
+	gittest.Git(t, remote, "init", "--bare")
+
+	// the branch in the fork diverged from the base
+	gittest.Git(t, dir, "checkout", "-b", "diverged")
+	gittest.Git(t, dir, "commit", "--allow-empty", "--message", "unrelated")
+	gittest.Git(t, dir, "push", remote, "HEAD:refs/heads/synchronize")
+
+	gittest.Git(t, dir, "checkout", "-b", "synchronize", base)
+	if err := os.WriteFile(filepath.Join(dir, "synchronized.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Git(t, dir, "add", ".")
+	gittest.Git(t, dir, "commit", "--message", "synchronize")
+	head := gittest.Git(t, dir, "rev-parse", "HEAD")
+
+	if err := PushForkBranch(ctx, logger, dir, remote, "synchronize", base, true, true, io.Discard, io.Discard, false); err != nil {
+		t.Fatalf("expected the reset branch to accept the synchronization as a fast-forward: %v", err)
+	}
+	if pushed := gittest.Git(t, remote, "rev-parse", "refs/heads/synchronize"); pushed != head {
+		t.Errorf("expected the fork branch at %s, got %s", head, pushed)
+	}
+
+	// without the reset, the diverged branch is not overwritten
+	gittest.Git(t, dir, "push", "--force", remote, "diverged:refs/heads/synchronize")
+	if err := PushForkBranch(ctx, logger, dir, remote, "synchronize", base, false, true, io.Discard, io.Discard, false); err == nil {
+		t.Error("expected pushing onto the diverged branch to fail with --no-force-push")
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"html"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,9 +24,34 @@ type Commit struct {
 	Author  string    `json:"author,omitempty"`
 	Message string    `json:"message,omitempty"`
 	Repo    string    `json:"repo,omitempty"`
+
+	// Precheck records the result of test-applying the commit, when that has been done.
+	Precheck string `json:"-"`
+}
+
+// DroppedCommit is a downstream commit that was intentionally not carried, with the reason it was dropped.
+type DroppedCommit struct {
+	Commit
+	Reason string `json:"reason"`
 }
 
+// Info looks up the commit sha in the repository in dir. Lookups of full commit hashes are cached in the context's
+// InfoCache, if it has one, as they cannot change.
 func Info(ctx context.Context, logger *logrus.Entry, sha, dir string) (Commit, error) {
+	cache, cached := ctx.Value(infoCacheKey{}).(*InfoCache)
+	if cached && fullShaRegex.MatchString(sha) {
+		if commit, ok := cache.get(dir, sha); ok {
+			return commit, nil
+		}
+	}
+	commit, err := info(ctx, logger, sha, dir)
+	if err == nil && cached && fullShaRegex.MatchString(sha) {
+		cache.add(dir, sha, commit)
+	}
+	return commit, err
+}
+
+func info(ctx context.Context, logger *logrus.Entry, sha, dir string) (Commit, error) {
 	infoCmd := WithDir(exec.CommandContext(ctx,
 		"git", "show",
 		sha,
@@ -60,10 +87,40 @@ func ParseFormat(format string) (Commit, error) {
 	}, nil
 }
 
+// UpstreamCommitRegex matches the commit-checker's expected format for downstream commit messages.
+var UpstreamCommitRegex = regexp.MustCompile(`^UPSTREAM: (revert: )?(([\w.-]+/[\w-.-]+)?: )?(\d+:|<carry>:|<drop>:)`)
+
+// UpstreamPR determines the upstream repository and pull request number a commit was cherry-picked from, using the
+// `UPSTREAM: 1234:` or `UPSTREAM: org/repo: 1234:` formats, the former referring to the repository of the commit in
+// the upstream org. An empty number is returned for other commits.
+func UpstreamPR(org string, commit Commit) (repo, number string) {
+	matches := UpstreamCommitRegex.FindStringSubmatch(commit.Message)
+	if len(matches) == 0 {
+		return "", ""
+	}
+	number = strings.TrimSuffix(matches[4], ":")
+	if strings.HasPrefix(number, "<") {
+		return "", ""
+	}
+	repo = matches[3]
+	if repo == "" {
+		repo = org + "/" + commit.Repo
+	}
+	return repo, number
+}
+
 func Table(logger *logrus.Logger, commits []Commit, repoBase string) {
 	writer := tabwriter.NewWriter(bumper.HideSecretsWriter{Delegate: os.Stdout, Censor: secret.Censor}, 0, 4, 2, ' ', 0)
 	for _, commit := range commits {
-		if _, err := fmt.Fprintln(writer, commit.Date.Format(time.DateTime)+"\t"+repoBase+commit.Repo+"\t", commit.Hash+"\t"+commit.Author+"\t"+commit.Message); err != nil {
+		pr := "-"
+		// only the number is shown, so the org of the repository does not matter
+		if _, number := UpstreamPR("", commit); number != "" {
+			pr = "#" + number
+		}
+		if commit.Precheck != "" {
+			pr += "\t" + commit.Precheck
+		}
+		if _, err := fmt.Fprintln(writer, commit.Date.Format(time.DateTime)+"\t"+repoBase+commit.Repo+"\t", commit.Hash+"\t"+pr+"\t"+commit.Author+"\t"+commit.Message); err != nil {
 			logger.WithError(err).Error("failed to write output")
 		}
 	}
@@ -72,16 +129,223 @@ func Table(logger *logrus.Logger, commits []Commit, repoBase string) {
 	}
 }
 
-func GetBody(commits []Commit, assign []string) string {
-	lines := []string{
-		"The staging/ and vendor/ directories have been synchronized from the upstream repositories, pulling in the following commits:",
+// maxBodySize is the largest pull request body GitHub accepts.
+const maxBodySize = 65536
+
+// The priorities of the sections of a pull request body. When the body does not fit, rows are dropped from the
+// sections with the lowest priority first.
+const (
+	priorityDropped = iota
+	priorityCommits
+	priorityDependencies
+	priorityTarget
+)
+
+// bodySection is one part of the details of a pull request body: a table, or a few lines of prose with no rows.
+type bodySection struct {
+	priority int
+	header   []string
+	rows     []string
+	footer   []string
+	// omitted formats the row noting how many rows are not shown. It may be nil for sections that are never
+	// partially shown.
+	omitted func(n int) string
+	// more counts the rows that were left out before truncation, e.g. by --body-max-carries
+	more int
+	// escaped sections use HTML, so their rows were escaped as they were formatted
+	escaped bool
+	// removed is set once the whole section has been dropped to fit the body
+	removed bool
+}
+
+func (s *bodySection) String() string {
+	if s.removed {
+		return ""
+	}
+	lines := append([]string{}, s.header...)
+	lines = append(lines, s.rows...)
+	if s.more > 0 && s.omitted != nil {
+		lines = append(lines, s.omitted(s.more))
+	}
+	lines = append(lines, s.footer...)
+	joined := strings.Join(lines, "\n")
+	if !s.escaped {
+		joined = html.EscapeString(joined)
+	}
+	return joined
+}
+
+func renderSections(sections []*bodySection) string {
+	var parts []string
+	for _, section := range sections {
+		if !section.removed {
+			parts = append(parts, section.String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// trimSections frees at least excess bytes from the lowest-priority section that still has content, dropping its
+// rows from the end and then the section itself. It returns false when there is nothing left to drop.
+func trimSections(sections []*bodySection, excess int) bool {
+	var lowest *bodySection
+	for _, section := range sections {
+		if !section.removed && (lowest == nil || section.priority < lowest.priority) {
+			lowest = section
+		}
+	}
+	if lowest == nil {
+		return false
+	}
+	if len(lowest.rows) == 0 || lowest.omitted == nil {
+		lowest.removed = true
+		return true
+	}
+	// leave room for the row noting what was omitted, which grows with the count
+	excess += len(lowest.omitted(lowest.more + len(lowest.rows)))
+	freed := 0
+	for len(lowest.rows) > 0 && freed < excess {
+		last := lowest.rows[len(lowest.rows)-1]
+		freed += len(last) + 1
+		lowest.rows = lowest.rows[:len(lowest.rows)-1]
+		lowest.more++
+	}
+	return true
+}
+
+func diffStatSection(stat *DiffStat) *bodySection {
+	if stat == nil {
+		return &bodySection{removed: true}
+	}
+	return &bodySection{
+		priority: priorityTarget,
+		header: []string{
+			"",
+			fmt.Sprintf("Changes outside of `vendor/`: %s. Changes to `vendor/`: %s.", stat.Source, stat.Vendor),
+		},
+	}
+}
+
+// maxDependencySectionSize is the size budget for the dependency changes section, so that it cannot crowd out the
+// rest of the body.
+const maxDependencySectionSize = 16384
+
+func dependencySection(changes []DependencyChange) *bodySection {
+	section := &bodySection{
+		priority: priorityDependencies,
+		omitted: func(n int) string {
+			return fmt.Sprintf("|... %d more|||", n)
+		},
+	}
+	if len(changes) == 0 {
+		section.removed = true
+		return section
+	}
+	section.header = []string{
+		"",
+		"The following dependency changes were made to `go.mod`:",
 		"",
-		"| Date | Commit | Author | Message |",
-		"| -    | -      | -      | -       |",
+		"| Module | Old | New |",
+		"| -      | -   | -   |",
+	}
+	size := 0
+	for i, change := range changes {
+		line := fmt.Sprintf("|%s|%s|%s|", change.Module, change.Old, change.New)
+		size += len(line) + 1
+		if size > maxDependencySectionSize {
+			section.more = len(changes) - i
+			break
+		}
+		section.rows = append(section.rows, line)
+	}
+	return section
+}
+
+// BodyData is the data passed to pull request body templates.
+type BodyData struct {
+	// Target is the upstream commit the downstream repository was updated through, for v1 repositories.
+	Target *Commit
+	// Commits are the commits that were synchronized or carried.
+	Commits []Commit
+	// Assign are the users and groups assigned to the pull request.
+	Assign []string
+	// DiffStat summarizes the changes made by the synchronization, if it was computed.
+	DiffStat *DiffStat
+	// Details are the rendered tables of commits and dependency changes.
+	Details string
+}
+
+// DefaultBodyTemplate is the pull request body template used for v0 when no other template is given.
+const DefaultBodyTemplate = `The staging/ and vendor/ directories have been synchronized from the upstream repositories, pulling in the following commits:
+
+{{.Details}}
+
+This pull request is expected to merge without any human intervention. If tests are failing here, changes must land upstream to fix any issues so that future downstreaming efforts succeed.
+{{range .Assign}}
+/cc @{{.}}{{end}}`
+
+// DefaultBodyTemplateV1 is the pull request body template used for v1 when no other template is given. The details
+// are already escaped, so only the assignees are escaped here.
+const DefaultBodyTemplateV1 = `The downstream repository has been updated through the following upstream commit:
+
+{{.Details}}
+
+This pull request is expected to merge without any human intervention. If tests are failing here, changes must land upstream to fix any issues so that future downstreaming efforts succeed.
+{{range .Assign}}
+/cc @{{html .}}{{end}}`
+
+// goModBodyTemplate is the pull request body template used when only the go.mod replaces are rewritten.
+const goModBodyTemplate = `The downstream repositories are already synchronized with upstream, so only the go.mod replace directives have been updated to point at their current downstream HEADs:
+
+{{.Details}}
+
+This pull request is expected to merge without any human intervention.
+{{range .Assign}}
+/cc @{{html .}}{{end}}`
+
+var (
+	defaultBodyTemplate   = template.Must(template.New("body").Parse(DefaultBodyTemplate))
+	defaultBodyTemplateV1 = template.Must(template.New("body").Parse(DefaultBodyTemplateV1))
+	defaultGoModBody      = template.Must(template.New("body").Parse(goModBodyTemplate))
+)
+
+// renderBody renders the pull request body from tmpl with the sections as its details. While the body is too large
+// for GitHub, rows and then whole sections are dropped, lowest priority first. Only if that is not enough, e.g.
+// because the template does not use the details, is the body cut short.
+func renderBody(tmpl *template.Template, data BodyData, sections []*bodySection) (string, error) {
+	for {
+		data.Details = renderSections(sections)
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render pull request body: %w", err)
+		}
+		body := buf.String()
+		if len(body) < maxBodySize {
+			return body, nil
+		}
+		if !trimSections(sections, len(body)-maxBodySize+1) {
+			return body[:maxBodySize-6] + "...", nil
+		}
+	}
+}
+
+// GetBody renders the pull request body for v0. If stat is set, the size of the changes is included. If tmpl is nil,
+// DefaultBodyTemplate is used.
+func GetBody(tmpl *template.Template, commits []Commit, changes []DependencyChange, stat *DiffStat, assign []string) (string, error) {
+	commitSection := &bodySection{
+		priority: priorityCommits,
+		header: []string{
+			"| Date | Commit | Author | Message |",
+			"| -    | -      | -      | -       |",
+		},
+		omitted: func(n int) string {
+			return fmt.Sprintf("||+%d more commits|||", n)
+		},
+		escaped: true,
 	}
 	for _, commit := range commits {
-		lines = append(
-			lines,
+		commitSection.rows = append(
+			commitSection.rows,
 			fmt.Sprintf("|%s|[operator-framework/%s@%s](https://github.com/operator-framework/%s/commit/%s)|%s|%s|",
 				commit.Date.Format(time.DateTime),
 				commit.Repo,
@@ -93,71 +357,195 @@ func GetBody(commits []Commit, assign []string) string {
 			),
 		)
 	}
-	lines = append(lines, "", "This pull request is expected to merge without any human intervention. If tests are failing here, changes must land upstream to fix any issues so that future downstreaming efforts succeed.", "")
-	for _, who := range assign {
-		lines = append(lines, fmt.Sprintf("/cc @%s", who))
-	}
+	dependencies := dependencySection(changes)
+	// the v0 body has never been escaped
+	dependencies.escaped = true
 
-	body := strings.Join(lines, "\n")
+	if tmpl == nil {
+		tmpl = defaultBodyTemplate
+	}
+	return renderBody(tmpl, BodyData{
+		Commits:  commits,
+		Assign:   assign,
+		DiffStat: stat,
+	}, []*bodySection{commitSection, diffStatSection(stat), dependencies})
+}
 
-	if len(body) >= 65536 {
-		body = body[:65530] + "..."
+// GetBodyV1 renders the pull request body for a v1 repository synchronized from the upstream org. If maxCarries is positive, at most that many
+// carried commits are listed. If stat is set, the size of the changes is included. If compareHead is set, a link
+// comparing it against compareBase is included. If tmpl is nil, DefaultBodyTemplateV1 is used.
+func GetBodyV1(tmpl *template.Template, org string, target Commit, tags []string, commits []Commit, maxCarries int, dropped []DroppedCommit, changes []DependencyChange, stat *DiffStat, compareBase, compareHead string, assign []string) (string, error) {
+	if tmpl == nil {
+		tmpl = defaultBodyTemplateV1
 	}
+	return renderBody(tmpl, BodyData{
+		Target:   &target,
+		Commits:  commits,
+		Assign:   assign,
+		DiffStat: stat,
+	}, append(targetSections(org, target, tags, commits, maxCarries), compareSection(target.Repo, compareBase, compareHead), diffStatSection(stat), dependencySection(changes), droppedSection(dropped)))
+}
 
-	return body
+// RepoBodyV1 is what a combined v1 pull request body lists for one of the repositories it synchronizes.
+type RepoBodyV1 struct {
+	Repo    string
+	Target  Commit
+	Tags    []string
+	Commits []Commit
+	Dropped []DroppedCommit
 }
 
-func GetBodyV1(target Commit, commits []Commit, assign []string) string {
-	lines := []string{
-		"The downstream repository has been updated through the following upstream commit:",
-		"",
-		"| Date | Commit | Author | Message |",
-		"| -    | -      | -      | -       |",
-	}
-	lines = append(lines, fmt.Sprintf("|%s|[operator-framework/%s@%s](https://github.com/operator-framework/%s/commit/%s)|%s|%s|",
-		target.Date.Format(time.DateTime),
-		target.Repo,
-		target.Hash[0:7],
-		target.Repo,
-		target.Hash,
-		target.Author,
-		target.Message,
-	))
-	lines = append(lines, fmt.Sprintf("||[upstream commit list](https://github.com/operator-framework/%s/commits/%s)|||",
-		target.Repo,
-		target.Hash,
-	))
-	lines = append(lines,
-		"",
-		"The `vendor/` directory has been updated and the following commits were carried:",
-		"",
-		"| Date | Commit | Author | Message |",
-		"| -    | -      | -      | -       |",
-	)
-	for _, commit := range commits {
-		lines = append(
-			lines,
-			fmt.Sprintf("|%s|[openshift/operator-framework-%s@%s](https://github.com/openshift/operator-framework-%s/commit/%s)|%s|%s|",
+// GetCombinedBodyV1 renders the body of a v1 pull request that synchronizes several repositories sharing a
+// checkout, listing the target and carried commits of each under a heading of its own. The first repository is
+// the one the body template is given as the target.
+func GetCombinedBodyV1(tmpl *template.Template, org string, repos []RepoBodyV1, maxCarries int, changes []DependencyChange, stat *DiffStat, compareBase, compareHead string, assign []string) (string, error) {
+	if len(repos) == 0 {
+		return "", fmt.Errorf("no repositories to describe")
+	}
+	var sections []*bodySection
+	var commits []Commit
+	var dropped []DroppedCommit
+	for _, repo := range repos {
+		sections = append(sections, &bodySection{
+			priority: priorityTarget,
+			header:   []string{"", fmt.Sprintf("### openshift/operator-framework-%s", repo.Repo), ""},
+		})
+		sections = append(sections, targetSections(org, repo.Target, repo.Tags, repo.Commits, maxCarries)...)
+		commits = append(commits, repo.Commits...)
+		dropped = append(dropped, repo.Dropped...)
+	}
+	if tmpl == nil {
+		tmpl = defaultBodyTemplateV1
+	}
+	return renderBody(tmpl, BodyData{
+		Target:   &repos[0].Target,
+		Commits:  commits,
+		Assign:   assign,
+		DiffStat: stat,
+	}, append(sections, compareSection(repos[0].Target.Repo, compareBase, compareHead), diffStatSection(stat), dependencySection(changes), droppedSection(dropped)))
+}
+
+// targetSections lists the upstream target of a v1 repository in org and the commits carried on top of it.
+func targetSections(org string, target Commit, tags []string, commits []Commit, maxCarries int) []*bodySection {
+	targetSection := &bodySection{
+		priority: priorityTarget,
+		header: []string{
+			"| Date | Commit | Author | Message |",
+			"| -    | -      | -      | -       |",
+			fmt.Sprintf("|%s|[%s/%s@%s](https://github.com/%s/%s/commit/%s)|%s|%s|",
+				target.Date.Format(time.DateTime),
+				org,
+				target.Repo,
+				target.Hash[0:7],
+				org,
+				target.Repo,
+				target.Hash,
+				target.Author,
+				target.Message,
+			),
+			fmt.Sprintf("||[upstream commit list](https://github.com/%s/%s/commits/%s)|||",
+				org,
+				target.Repo,
+				target.Hash,
+			),
+		},
+	}
+	if len(tags) > 0 {
+		targetSection.header = append(targetSection.header, "", fmt.Sprintf("This update crosses the following upstream tags: `%s`", strings.Join(tags, "`, `")))
+	}
+
+	carrySection := &bodySection{
+		priority: priorityCommits,
+		header: []string{
+			"",
+			"The `vendor/` directory has been updated and the following commits were carried:",
+			"",
+			"| Date | Commit | Upstream PR | Author | Message |",
+			"| -    | -      | -           | -      | -       |",
+		},
+		omitted: func(n int) string {
+			return fmt.Sprintf("||+%d more carried commits||||", n)
+		},
+	}
+	shown := commits
+	if maxCarries > 0 && len(commits) > maxCarries {
+		shown = commits[:maxCarries]
+	}
+	for _, commit := range shown {
+		pr := "-"
+		if repo, number := UpstreamPR(org, commit); number != "" {
+			pr = fmt.Sprintf("[%s#%s](https://github.com/%s/pull/%s)", repo, number, repo, number)
+		}
+		carrySection.rows = append(
+			carrySection.rows,
+			fmt.Sprintf("|%s|[openshift/operator-framework-%s@%s](https://github.com/openshift/operator-framework-%s/commit/%s)|%s|%s|%s|",
 				commit.Date.Format(time.DateTime),
 				commit.Repo,
 				commit.Hash[0:7],
 				commit.Repo,
 				commit.Hash,
+				pr,
 				commit.Author,
 				commit.Message,
 			),
 		)
 	}
-	lines = append(lines, "", "This pull request is expected to merge without any human intervention. If tests are failing here, changes must land upstream to fix any issues so that future downstreaming efforts succeed.", "")
-	for _, who := range assign {
-		lines = append(lines, fmt.Sprintf("/cc @%s", who))
+	carrySection.more = len(commits) - len(shown)
+	return []*bodySection{targetSection, carrySection}
+}
+
+// compareSection links to the compare view of the downstream changes, which stands in for the carried commits, so
+// it is kept along with the target.
+func compareSection(repo, compareBase, compareHead string) *bodySection {
+	section := &bodySection{priority: priorityTarget, removed: compareHead == ""}
+	if compareHead != "" {
+		section.header = []string{"", fmt.Sprintf("The full set of downstream changes can be reviewed in the [compare view](https://github.com/openshift/operator-framework-%s/compare/%s...%s).",
+			repo,
+			compareBase,
+			compareHead,
+		)}
 	}
+	return section
+}
 
-	body := strings.Join(lines, "\n")
+// GetGoModBody formats the pull request body for a rewrite of just the go.mod replaces, listing the dependency
+// changes it makes.
+func GetGoModBody(changes []DependencyChange, assign []string) (string, error) {
+	return renderBody(defaultGoModBody, BodyData{Assign: assign}, []*bodySection{dependencySection(changes)})
+}
 
-	if len(body) >= 65536 {
-		body = body[:65530] + "..."
+// droppedSection lists the dropped commits in a collapsed HTML block, so only its rows are escaped.
+func droppedSection(dropped []DroppedCommit) *bodySection {
+	section := &bodySection{
+		priority: priorityDropped,
+		header: []string{
+			"",
+			"<details>",
+			"<summary>Dropped commits</summary>",
+			"",
+			"| Commit | Reason | Author | Message |",
+			"| -      | -      | -      | -       |",
+		},
+		footer: []string{"", "</details>"},
+		omitted: func(n int) string {
+			return fmt.Sprintf("||+%d more dropped commits|||", n)
+		},
+		escaped: true,
+		removed: len(dropped) == 0,
 	}
-
-	return html.EscapeString(body)
+	for _, commit := range dropped {
+		section.rows = append(
+			section.rows,
+			fmt.Sprintf("|[openshift/operator-framework-%s@%s](https://github.com/openshift/operator-framework-%s/commit/%s)|%s|%s|%s|",
+				commit.Repo,
+				commit.Hash[0:7],
+				commit.Repo,
+				commit.Hash,
+				commit.Reason,
+				html.EscapeString(commit.Author),
+				html.EscapeString(commit.Message),
+			),
+		)
+	}
+	return section
 }
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetCombinedBodyV1(t *testing.T) {
+	commit := func(repo, hash, message string) Commit {
+		return Commit{Repo: repo, Hash: strings.Repeat(hash, 40), Author: "someone", Message: message}
+	}
+	body, err := GetCombinedBodyV1(nil, "example-org", []RepoBodyV1{
+		{
+			Repo:    "operator-controller",
+			Target:  commit("operator-controller", "1", "upstream target"),
+			Tags:    []string{"v1.2.0"},
+			Commits: []Commit{commit("operator-controller", "a", "UPSTREAM: <carry>: first")},
+		},
+		{
+			Repo:    "catalogd",
+			Target:  commit("operator-controller", "1", "upstream target"),
+			Commits: []Commit{commit("catalogd", "b", "UPSTREAM: <carry>: second"), commit("catalogd", "d", "UPSTREAM: 123: third")},
+			Dropped: []DroppedCommit{{Commit: commit("catalogd", "c", "UPSTREAM: <drop>: generated"), Reason: "message-drop"}},
+		},
+	}, 0, nil, nil, "main", "someone:fork:branch", []string{"reviewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"### openshift/operator-framework-operator-controller",
+		"### openshift/operator-framework-catalogd",
+		"UPSTREAM: &lt;carry&gt;: first",
+		"UPSTREAM: &lt;carry&gt;: second",
+		"`v1.2.0`",
+		"[example-org/operator-controller@1111111](https://github.com/example-org/operator-controller/commit/",
+		"(https://github.com/example-org/operator-controller/commits/",
+		"[example-org/catalogd#123](https://github.com/example-org/catalogd/pull/123)",
+		"UPSTREAM: &lt;drop&gt;: generated",
+		"compare/main...someone:fork:branch",
+		"/cc @reviewer",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected the body to contain %q:\n%s", expected, body)
+		}
+	}
+	if strings.Index(body, "first") > strings.Index(body, "### openshift/operator-framework-catalogd") {
+		t.Errorf("expected the carries of each repo to be listed under its heading:\n%s", body)
+	}
+	if strings.Count(body, "compare view") != 1 {
+		t.Errorf("expected the compare view of the shared checkout to be linked once:\n%s", body)
+	}
+
+	if _, err := GetCombinedBodyV1(nil, "example-org", nil, 0, nil, nil, "", "", nil); err == nil {
+		t.Error("expected an error for a body without repositories")
+	}
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"container/list"
+	"context"
+	"regexp"
+	"sync"
+)
+
+// maxInfoCacheEntries bounds the number of commits an InfoCache remembers, evicting the least recently used.
+const maxInfoCacheEntries = 4096
+
+// fullShaRegex matches full commit hashes, the only revisions whose commits cannot change and so can be cached.
+var fullShaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type infoCacheKey struct{}
+
+type infoCacheEntry struct {
+	dir, sha string
+	commit   Commit
+}
+
+// InfoCache remembers the commits that Info looked up during a run, keyed by directory and hash, as the same commit
+// is often looked up several times, e.g. to check whether it is missing and to render it. It is safe for concurrent
+// use.
+type InfoCache struct {
+	lock    sync.Mutex
+	order   *list.List
+	entries map[[2]string]*list.Element
+}
+
+// WithInfoCache returns a context in which Info caches its lookups, for the duration of a run.
+func WithInfoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, infoCacheKey{}, &InfoCache{
+		order:   list.New(),
+		entries: map[[2]string]*list.Element{},
+	})
+}
+
+func (c *InfoCache) get(dir, sha string) (Commit, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[[2]string{dir, sha}]
+	if !ok {
+		return Commit{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*infoCacheEntry).commit, true
+}
+
+func (c *InfoCache) add(dir, sha string, commit Commit) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := [2]string{dir, sha}
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&infoCacheEntry{dir: dir, sha: sha, commit: commit})
+	if c.order.Len() > maxInfoCacheEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry := oldest.Value.(*infoCacheEntry)
+		delete(c.entries, [2]string{entry.dir, entry.sha})
+	}
+}
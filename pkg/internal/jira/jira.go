@@ -0,0 +1,138 @@
+// Package jira reports published synchronizations on the Jira issue that tracks them. Reporting is informational, so
+// failures are logged rather than returned.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/config/secret"
+)
+
+// Sync describes the pull request published for one downstream repository.
+type Sync struct {
+	Repo        string
+	PullRequest string
+	Target      string
+	Carries     int
+}
+
+// Client comments on and transitions one Jira issue through the Jira REST API.
+type Client struct {
+	url   string
+	issue string
+	token func() []byte
+}
+
+// NewClient creates a client for issue on the Jira server at baseURL, authenticating with the personal access token
+// at tokenPath.
+func NewClient(baseURL, tokenPath, issue string) (*Client, error) {
+	if err := secret.Add(tokenPath); err != nil {
+		return nil, fmt.Errorf("failed to add Jira token to secret agent: %w", err)
+	}
+	return &Client{
+		url:   strings.TrimSuffix(baseURL, "/"),
+		issue: issue,
+		token: secret.GetTokenGenerator(tokenPath),
+	}, nil
+}
+
+// Comment formats the comment reporting the synchronizations.
+func Comment(syncs []Sync) string {
+	lines := []string{"The downstream repositories have been synchronized from upstream:", ""}
+	for _, sync := range syncs {
+		lines = append(lines, fmt.Sprintf("* %s: %s, through upstream commit %s with %d carried commits", sync.Repo, sync.PullRequest, sync.Target, sync.Carries))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Report comments on the issue with the synchronizations, then moves it through the transition with the given name,
+// if one is given. Failures are logged but not returned.
+func (c *Client) Report(ctx context.Context, logger *logrus.Entry, syncs []Sync, transition string, dryRun bool) {
+	if len(syncs) == 0 {
+		return
+	}
+	logger = logger.WithField("issue", c.issue)
+	comment := Comment(syncs)
+	if dryRun {
+		logger.WithField("comment", comment).Info("would comment on Jira issue")
+		if transition != "" {
+			logger.Infof("would transition Jira issue through %q", transition)
+		}
+		return
+	}
+
+	if err := c.do(ctx, http.MethodPost, "comment", map[string]string{"body": comment}, nil); err != nil {
+		logger.WithError(err).Warn("failed to comment on Jira issue")
+		return
+	}
+	if transition == "" {
+		return
+	}
+	if err := c.transition(ctx, transition); err != nil {
+		logger.WithError(err).Warn("failed to transition Jira issue")
+	}
+}
+
+func (c *Client) transition(ctx context.Context, name string) error {
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "transitions", nil, &available); err != nil {
+		return err
+	}
+	var names []string
+	for _, transition := range available.Transitions {
+		if strings.EqualFold(transition.Name, name) {
+			return c.do(ctx, http.MethodPost, "transitions", map[string]any{"transition": map[string]string{"id": transition.ID}}, nil)
+		}
+		names = append(names, transition.Name)
+	}
+	return fmt.Errorf("transition %q is not available, only %q", name, names)
+}
+
+// do sends a request to the issue's endpoint, encoding in and decoding the response into out when they are set.
+func (c *Client) do(ctx context.Context, method, endpoint string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/rest/api/2/issue/%s/%s", c.url, url.PathEscape(c.issue), endpoint), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(c.token())))
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Jira: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s", method, endpoint, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Lag describes how far a downstream repository is behind its upstream target.
+type Lag struct {
+	Repo    string        `json:"repo"`
+	Commits int           `json:"commits"`
+	Behind  time.Duration `json:"behind"`
+}
+
+// PrintLag writes lags to out as a table, or as JSON.
+func PrintLag(out io.Writer, lags []Lag, asJSON bool) error {
+	if asJSON {
+		raw, err := json.MarshalIndent(lags, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not marshal lag: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(raw))
+		return err
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if _, err := fmt.Fprintln(writer, "REPO\tCOMMITS\tBEHIND"); err != nil {
+		return err
+	}
+	for _, lag := range lags {
+		if _, err := fmt.Fprintln(writer, lag.Repo+"\t"+strconv.Itoa(lag.Commits)+"\t"+formatBehind(lag.Behind)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// formatBehind rounds d to the largest whole unit, e.g. 3 days, as more precision is noise at these time scales.
+func formatBehind(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	default:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+}
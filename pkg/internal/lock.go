@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lockFile is the name of the lock file, kept in the git directory so that it is never committed.
+const lockFile = "operator-framework-tooling.lock"
+
+// maxLockAge is the age after which a lock is considered stale even if its process seems to be running, since the
+// process ID may have been reused.
+const maxLockAge = 24 * time.Hour
+
+// LockRepo takes the lock on the repository in dir, so that two runs do not modify it concurrently, failing if another
+// run holds it. A lock left behind by a run that crashed is taken over. The returned function releases the lock.
+func LockRepo(ctx context.Context, logger *logrus.Entry, dir string) (func(), error) {
+	gitDir, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", "--absolute-git-dir",
+	), dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git directory: %w", err)
+	}
+	path := filepath.Join(strings.TrimSpace(gitDir), lockFile)
+
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d %s\n", os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to write lock file: %w", err)
+			}
+			logger.WithField("lock", path).Debug("took repository lock")
+			return func() {
+				if err := os.Remove(path); err != nil {
+					logger.WithError(err).WithField("lock", path).Warn("failed to release repository lock")
+				}
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		pid, started, stale := staleLock(path)
+		if !stale {
+			// the other sync will be done eventually, so this one can be retried
+			return nil, WithExitCode(ExitTransient, fmt.Errorf("another sync is in progress in %s (pid %d, started %s); if it is not, remove %s", dir, pid, started.Format(time.RFC3339), path))
+		}
+		logger.WithFields(logrus.Fields{"lock": path, "pid": pid}).Warn("taking over stale repository lock")
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+}
+
+// staleLock reads the process ID and start time recorded in the lock file at path, and determines whether the lock
+// was left behind: its process is gone, it is too old, or it cannot be read at all.
+func staleLock(path string) (int, time.Time, bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, time.Time{}, true
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) != 2 {
+		return 0, time.Time{}, true
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, time.Time{}, true
+	}
+	started, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil || time.Since(started) > maxLockAge {
+		return pid, started, true
+	}
+	// signal 0 only checks that the process exists; a permission error means it does, under another user
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return pid, started, true
+	}
+	if err := process.Signal(syscall.Signal(0)); err != nil && !errors.Is(err, syscall.EPERM) {
+		return pid, started, true
+	}
+	return pid, started, false
+}
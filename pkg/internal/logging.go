@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// outputLock serializes the flushes of worker loggers, so that each flush is written out in one piece.
+var outputLock sync.Mutex
+
+// bufferedOutput collects the log lines of one worker until they are flushed.
+type bufferedOutput struct {
+	out io.Writer
+
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *bufferedOutput) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *bufferedOutput) flush() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	_, err := b.out.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+// WorkerLogger derives a logger for one worker of a parallel phase, labelling its lines with the worker id. When
+// running concurrently, the lines are buffered until FlushLog is called - RunCommand does so after each command - so
+// that the output of concurrent commands is not interleaved. When concurrency is 1, the lines are written directly.
+func WorkerLogger(logger *logrus.Entry, id string, concurrency int) *logrus.Entry {
+	labelled := logger.WithField("worker", id)
+	if concurrency <= 1 {
+		return labelled
+	}
+	worker := logrus.New()
+	worker.Out = &bufferedOutput{out: logger.Logger.Out}
+	worker.Formatter = logger.Logger.Formatter
+	worker.Hooks = logger.Logger.Hooks
+	worker.ReportCaller = logger.Logger.ReportCaller
+	worker.ExitFunc = logger.Logger.ExitFunc
+	worker.SetLevel(logger.Logger.GetLevel())
+	return worker.WithContext(logger.Context).WithFields(labelled.Data)
+}
+
+// FlushLog writes out the lines buffered by a logger from WorkerLogger. It is a no-op for other loggers.
+func FlushLog(logger *logrus.Entry) {
+	out, ok := logger.Logger.Out.(*bufferedOutput)
+	if !ok {
+		return
+	}
+	if err := out.flush(); err != nil {
+		logrus.WithError(err).Error("failed to flush worker output")
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() (*logrus.Entry, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = out
+	logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true, DisableColors: true}
+	return logrus.NewEntry(logger), out
+}
+
+func TestWorkerLoggerPassthrough(t *testing.T) {
+	logger, out := newTestLogger()
+	worker := WorkerLogger(logger, "a", 1)
+	if worker.Logger != logger.Logger {
+		t.Fatal("expected a worker logger without concurrency to log through the parent logger")
+	}
+	worker.Info("working")
+	if !strings.Contains(out.String(), "worker=a") {
+		t.Errorf("expected the line to be written directly with the worker id, got %q", out.String())
+	}
+}
+
+func TestWorkerLoggerFlushesPerCommand(t *testing.T) {
+	logger, out := newTestLogger()
+	first := WorkerLogger(logger, "first", 2)
+	second := WorkerLogger(logger, "second", 2)
+	first.Info("first started")
+	second.Info("second started")
+	if out.Len() != 0 {
+		t.Fatalf("expected the lines of concurrent workers to be buffered, got %q", out.String())
+	}
+
+	if _, err := RunCommand(first, exec.Command("true")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "first started") || strings.Contains(out.String(), "second started") {
+		t.Fatalf("expected running a command to flush only the lines of its worker, got %q", out.String())
+	}
+
+	second.Info("second done")
+	if _, err := RunCommand(second, exec.Command("true")); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "second started") || !strings.Contains(lines[2], "second done") {
+		t.Errorf("expected the lines of the second worker to be flushed together after the first, got %q", lines)
+	}
+}
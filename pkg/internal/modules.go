@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ModuleCache remembers the results of `go list -m` lookups, keyed by directory and module.
+// Entries are invalidated when the go.mod in the directory has been modified since the lookup.
+// Lookups in a checkout of a known commit are keyed by the commit instead, and never invalidated.
+type ModuleCache struct {
+	path string
+
+	lock    sync.Mutex
+	entries map[string]moduleCacheEntry
+}
+
+type moduleCacheEntry struct {
+	Dir       string    `json:"dir"`
+	Commit    string    `json:"commit,omitempty"`
+	Module    string    `json:"module"`
+	GoModTime time.Time `json:"goModTime"`
+	Version   string    `json:"version"`
+}
+
+// LoadModuleCache creates a cache, seeding it from the file at path if it exists.
+// An empty path creates a cache that only lives for the duration of the run.
+func LoadModuleCache(path string) (*ModuleCache, error) {
+	cache := &ModuleCache{
+		path:    path,
+		entries: map[string]moduleCacheEntry{},
+	}
+	if path == "" {
+		return cache, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read module cache file: %w", err)
+	}
+	var entries []moduleCacheEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("could not unmarshal module cache file: %w", err)
+	}
+	for _, entry := range entries {
+		cache.entries[entry.key()] = entry
+	}
+	return cache, nil
+}
+
+// Save persists the cache to the file it was loaded from, if any. Entries keyed by a directory that no longer
+// exists, such as a temporary checkout, are dropped, as they can never be looked up again.
+func (c *ModuleCache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+	c.lock.Lock()
+	entries := make([]moduleCacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		if entry.Commit == "" {
+			if _, err := os.Stat(entry.Dir); errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	c.lock.Unlock()
+
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("could not marshal module cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, raw, 0666); err != nil {
+		return fmt.Errorf("could not write module cache file: %w", err)
+	}
+	return nil
+}
+
+// Version returns the version of module required by the go.mod in dir, running `go list -m` with goBin and goEnv
+// only when there is no valid cached result. When dir is a checkout of commit, the result is cached for the commit,
+// so that it is found again when the commit is checked out elsewhere; otherwise commit is empty.
+func (c *ModuleCache) Version(ctx context.Context, logger *logrus.Entry, goBin, dir, commit, module string, goEnv []string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("unable to canonicalize %q: %w", dir, err)
+	}
+	stat, err := os.Stat(filepath.Join(absDir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("failed to stat go.mod: %w", err)
+	}
+	key := moduleCacheEntry{Dir: absDir, Commit: commit, Module: module}.key()
+
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	c.lock.Unlock()
+	if ok && (commit != "" || entry.GoModTime.Equal(stat.ModTime())) {
+		logger.WithFields(logrus.Fields{"module": module, "version": entry.Version}).Debug("using cached module version")
+		return entry.Version, nil
+	}
+
+	rawInfo, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		goBin, "list", "-json", "-m", module,
+	), absDir), goEnv...))
+	if err != nil {
+		return "", fmt.Errorf("failed to determine dependent version for module %s: %w", module, err)
+	}
+	var info struct {
+		Version string `json:"Version"`
+	}
+	if err := json.Unmarshal([]byte(rawInfo), &info); err != nil {
+		return "", fmt.Errorf("failed to parse module version for %s: %w", module, err)
+	}
+
+	c.lock.Lock()
+	c.entries[key] = moduleCacheEntry{
+		Dir:       absDir,
+		Commit:    commit,
+		Module:    module,
+		GoModTime: stat.ModTime(),
+		Version:   info.Version,
+	}
+	c.lock.Unlock()
+	return info.Version, nil
+}
+
+func (e moduleCacheEntry) key() string {
+	if e.Commit != "" {
+		return e.Commit + "\x00" + e.Module
+	}
+	return e.Dir + "\x00" + e.Module
+}
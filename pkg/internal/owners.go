@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+type ownersFile struct {
+	Approvers []string `json:"approvers"`
+}
+
+type ownersAliasesFile struct {
+	Aliases map[string][]string `json:"aliases"`
+}
+
+// CommitOwners returns the approvers in the OWNERS files of dir closest to the paths changed by commits, with the
+// aliases from OWNERS_ALIASES expanded. The changed paths are relative to prefix in dir, which is where the commits
+// were applied. OWNERS files that are missing or cannot be parsed are skipped, so that at worst no owners are found.
+func CommitOwners(ctx context.Context, logger *logrus.Entry, dir, prefix string, commits []Commit) []string {
+	aliases := map[string][]string{}
+	if raw, err := os.ReadFile(filepath.Join(dir, "OWNERS_ALIASES")); err == nil {
+		var aliasesFile ownersAliasesFile
+		if err := yaml.Unmarshal(raw, &aliasesFile); err != nil {
+			logger.WithError(err).Warn("failed to parse OWNERS_ALIASES, not expanding aliases")
+		} else {
+			aliases = aliasesFile.Aliases
+		}
+	}
+
+	// approvers caches the approvers of the OWNERS file in each directory, nil if there is none
+	approvers := map[string][]string{}
+	ownersOf := func(ownersDir string) []string {
+		if cached, ok := approvers[ownersDir]; ok {
+			return cached
+		}
+		var owners ownersFile
+		raw, err := os.ReadFile(filepath.Join(dir, ownersDir, "OWNERS"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.WithError(err).WithField("dir", ownersDir).Warn("failed to read OWNERS")
+		} else if err == nil {
+			if err := yaml.Unmarshal(raw, &owners); err != nil {
+				logger.WithError(err).WithField("dir", ownersDir).Warn("failed to parse OWNERS")
+			}
+		}
+		approvers[ownersDir] = owners.Approvers
+		return owners.Approvers
+	}
+
+	var cc []string
+	for _, commit := range commits {
+		rawPaths, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+			"git", "diff-tree", "-r", "--root", "--no-commit-id", "--name-only", commit.Hash,
+		), dir))
+		if err != nil {
+			logger.WithError(err).WithField("commit", commit.Hash).Warn("failed to list changed paths, not looking up their owners")
+			continue
+		}
+		for _, path := range strings.Split(strings.TrimSpace(rawPaths), "\n") {
+			if path == "" {
+				continue
+			}
+			ownersDir := filepath.Dir(filepath.Join(prefix, path))
+			owners := ownersOf(ownersDir)
+			for len(owners) == 0 && ownersDir != "." {
+				ownersDir = filepath.Dir(ownersDir)
+				owners = ownersOf(ownersDir)
+			}
+			for _, owner := range owners {
+				members, isAlias := aliases[owner]
+				if !isAlias {
+					members = []string{owner}
+				}
+				for _, member := range members {
+					if !slices.Contains(cc, member) {
+						cc = append(cc, member)
+					}
+				}
+			}
+		}
+	}
+	return cc
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WarnFilteredDependencies warns about the commits that a path filter excluded from the revisions, with the log
+// arguments given, when a later commit that was included changes the same files: the included commit likely depends
+// on them, and may not apply or build without them.
+func WarnFilteredDependencies(ctx context.Context, logger *logrus.Entry, dir string, revisions []string, included []string) error {
+	raw, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"log", "--reverse", "--no-merges", "--name-only", "--format=%x00%H"}, revisions...)...,
+	), dir))
+	if err != nil {
+		return err
+	}
+	// excluded holds the latest excluded commit to change each file, so far
+	excluded := map[string]string{}
+	for _, entry := range strings.Split(raw, "\x00") {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		hash, files := lines[0], lines[1:]
+		if hash == "" {
+			continue
+		}
+		if !slices.ContainsFunc(included, func(commit string) bool { return strings.HasPrefix(hash, commit) }) {
+			for _, file := range files {
+				if file = strings.TrimSpace(file); file != "" {
+					excluded[file] = hash
+				}
+			}
+			continue
+		}
+		for _, file := range files {
+			if dependency, ok := excluded[strings.TrimSpace(file)]; ok {
+				logger.WithFields(logrus.Fields{"commit": hash, "dependency": dependency, "file": strings.TrimSpace(file)}).Warn("commit depends on a commit excluded by --path-filter, which changed the same file")
+			}
+		}
+	}
+	return nil
+}
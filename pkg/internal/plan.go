@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PlanMetadata records the upstream targets that the commits in a plan were detected against, so that replaying a
+// stale plan with --commits-input can be noticed.
+type PlanMetadata struct {
+	DetectedAt  time.Time         `json:"detectedAt"`
+	Targets     map[string]string `json:"targets"`
+	Fingerprint string            `json:"fingerprint"`
+}
+
+type plan struct {
+	Metadata *PlanMetadata   `json:"metadata"`
+	Commits  json.RawMessage `json:"commits"`
+}
+
+// NewPlanMetadata records targets, a mapping of repository to upstream commit, as detected now.
+func NewPlanMetadata(targets map[string]string) PlanMetadata {
+	metadata := PlanMetadata{
+		DetectedAt: time.Now().UTC().Truncate(time.Second),
+		Targets:    targets,
+	}
+	metadata.Fingerprint = metadata.fingerprint()
+	return metadata
+}
+
+func (m PlanMetadata) fingerprint() string {
+	var repos []string
+	for repo := range m.Targets {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	lines := []string{m.DetectedAt.Format(time.RFC3339)}
+	for _, repo := range repos {
+		lines = append(lines, repo+"="+m.Targets[repo])
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(lines, "\n"))))
+}
+
+// WritePlan writes the commits to path along with the plan metadata.
+func WritePlan(path string, metadata PlanMetadata, commits any) error {
+	rawCommits, err := json.Marshal(commits)
+	if err != nil {
+		return fmt.Errorf("could not marshal commits: %w", err)
+	}
+	rawPlan, err := json.Marshal(plan{Metadata: &metadata, Commits: rawCommits})
+	if err != nil {
+		return fmt.Errorf("could not marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, rawPlan, 0666); err != nil {
+		return fmt.Errorf("could not write commits: %w", err)
+	}
+	return nil
+}
+
+// ReadPlan reads the commits from path, returning the plan metadata. Plans written before metadata was recorded
+// are read as well, in which case no metadata is returned. As plans may be edited by hand, unknown fields in the
+// commits are rejected, and errors report where in the file they are.
+func ReadPlan(path string, commits any) (*PlanMetadata, error) {
+	rawPlan, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read input file: %w", err)
+	}
+	var p plan
+	if err := json.Unmarshal(rawPlan, &p); err != nil || p.Metadata == nil {
+		p = plan{Commits: rawPlan}
+	}
+	if err := decodeStrict(rawPlan, p.Commits, commits); err != nil {
+		return nil, fmt.Errorf("could not unmarshal input commits from %s: %w", path, err)
+	}
+	return p.Metadata, nil
+}
+
+// decodeStrict unmarshals raw, which is a part of file, into v, rejecting unknown fields. Errors are prefixed with
+// their line and column in file.
+func decodeStrict(file, raw []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+	// the decoder only knows that it was somewhere in the value it last read, so point at the culprit where possible
+	offset := decoder.InputOffset()
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	case errors.As(err, &timeErr):
+		if i := bytes.Index(raw, []byte(strconv.Quote(timeErr.Value))); i >= 0 {
+			offset = int64(i)
+		}
+	default:
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			if i := bytes.Index(raw, []byte(field)); i >= 0 {
+				offset = int64(i)
+			}
+		}
+	}
+	if base := bytes.Index(file, raw); base >= 0 {
+		offset += int64(base)
+	}
+	offset = min(max(offset, 0), int64(len(file)))
+	before := file[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// CheckPlan compares the targets recorded in the plan metadata to the current upstream targets, warning when the
+// plan is stale or failing if strict is set.
+func CheckPlan(logger *logrus.Entry, metadata *PlanMetadata, current map[string]string, strict bool) error {
+	if metadata == nil {
+		logger.Warn("commits input has no plan metadata, cannot determine whether it is stale")
+		return nil
+	}
+	var problems []string
+	if metadata.Fingerprint != metadata.fingerprint() {
+		problems = append(problems, "fingerprint does not match the recorded targets")
+	}
+	for repo, commit := range current {
+		if metadata.Targets[repo] != commit {
+			problems = append(problems, fmt.Sprintf("%s upstream is at %s, plan was detected at %s", repo, commit, metadata.Targets[repo]))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	logger = logger.WithFields(logrus.Fields{"detected-at": metadata.DetectedAt, "fingerprint": metadata.Fingerprint})
+	if strict {
+		return WithExitCode(ExitDivergence, fmt.Errorf("commits input is stale: %s", strings.Join(problems, "; ")))
+	}
+	for _, problem := range problems {
+		logger.Warnf("commits input may be stale: %s", problem)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureVerifier checks the signatures of commits against a trust store: the allowed signers file for SSH
+// signatures, and the GnuPG home directory for GPG signatures. Empty values use the ambient git and GnuPG
+// configuration.
+type SignatureVerifier struct {
+	AllowedSigners string
+	GPGHome        string
+}
+
+// signatureStatuses describes the signature statuses reported by git log --format=%G?.
+var signatureStatuses = map[string]string{
+	"B": "has a bad signature",
+	"U": "has a good signature of unknown validity",
+	"X": "has a good signature that has expired",
+	"Y": "has a good signature made by an expired key",
+	"R": "has a good signature made by a revoked key",
+	"E": "has a signature that cannot be checked, the key may be missing from the trust store",
+	"N": "is not signed",
+}
+
+// Verify fails if commit in dir is not signed, or its signature is not a good one made by a key in the trust store.
+func (v SignatureVerifier) Verify(ctx context.Context, logger *logrus.Entry, dir, commit string) error {
+	args := []string{"log", "-1", "--format=%G?%n%GS", commit}
+	if v.AllowedSigners != "" {
+		args = append([]string{"-c", "gpg.ssh.allowedSignersFile=" + v.AllowedSigners}, args...)
+	}
+	cmd := WithDir(exec.CommandContext(ctx, "git", args...), dir)
+	if v.GPGHome != "" {
+		cmd = WithEnv(cmd, append(os.Environ(), "GNUPGHOME="+v.GPGHome)...)
+	}
+	output, err := RunCommand(logger, cmd)
+	if err != nil {
+		return fmt.Errorf("failed to check signature of commit %s: %w", commit, err)
+	}
+	status, signer, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if status != "G" {
+		description, ok := signatureStatuses[status]
+		if !ok {
+			description = fmt.Sprintf("has an unknown signature status %q", status)
+		}
+		if status == "N" && v.AllowedSigners == "" {
+			// git cannot verify SSH signatures without allowed signers, and reports them as missing
+			description += ", or has an SSH signature and no allowed signers were given"
+		}
+		return fmt.Errorf("commit %s %s", commit, description)
+	}
+	logger.WithFields(logrus.Fields{"commit": commit, "signer": signer}).Info("verified commit signature")
+	return nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CheckFileSizes checks that none of the files added or modified by the commit at HEAD in dir is larger than
+// maxSize bytes. Oversized files fail the check, or are only logged when warnOnly is set.
+func CheckFileSizes(ctx context.Context, logger *logrus.Entry, dir string, maxSize int64, warnOnly bool) error {
+	rawChanges, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff-tree", "-r", "--no-commit-id", "--diff-filter=AM", "HEAD",
+	), dir))
+	if err != nil {
+		return err
+	}
+	// each line is formatted as `:<old mode> <new mode> <old blob> <new blob> <status>\t<path>`
+	var blobs, paths []string
+	for _, line := range strings.Split(rawChanges, "\n") {
+		info, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 5 || fields[1] == "160000" {
+			// submodules are not blobs
+			continue
+		}
+		blobs = append(blobs, fields[3])
+		paths = append(paths, path)
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	sizeCmd := WithDir(exec.CommandContext(ctx,
+		"git", "cat-file", "--batch-check=%(objectsize)",
+	), dir)
+	sizeCmd.Stdin = strings.NewReader(strings.Join(blobs, "\n") + "\n")
+	rawSizes, err := RunCommand(logger, sizeCmd)
+	if err != nil {
+		return err
+	}
+	sizes := strings.Fields(rawSizes)
+	if len(sizes) != len(blobs) {
+		return fmt.Errorf("expected sizes for %d files, got %q", len(blobs), rawSizes)
+	}
+
+	var oversized []string
+	for i, rawSize := range sizes {
+		size, err := strconv.ParseInt(rawSize, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size for %s: %w", paths[i], err)
+		}
+		if size > maxSize {
+			logger.WithFields(logrus.Fields{"path": paths[i], "size": size, "max": maxSize}).Warn("commit adds a file larger than the maximum size")
+			oversized = append(oversized, fmt.Sprintf("%s (%d bytes)", paths[i], size))
+		}
+	}
+	if len(oversized) > 0 && !warnOnly {
+		return fmt.Errorf("files larger than %d bytes: %s", maxSize, strings.Join(oversized, ", "))
+	}
+	return nil
+}
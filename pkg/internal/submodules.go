@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyncSubmodules brings the submodules in dir in line with .gitmodules when the commit at HEAD changed it, staging
+// the gitlinks and amending them into the commit if they differ.
+func SyncSubmodules(ctx context.Context, logger *logrus.Entry, dir string, commitArgs, env []string) error {
+	changed, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff-tree", "--no-commit-id", "--name-only", "-r", "HEAD", "--", ".gitmodules",
+	), dir))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(changed) == "" {
+		return nil
+	}
+	logger.Info("submodule changes detected, updating submodules")
+
+	for _, cmd := range []*exec.Cmd{
+		exec.CommandContext(ctx,
+			"git", "submodule", "sync", "--recursive",
+		),
+		exec.CommandContext(ctx,
+			"git", "submodule", "update", "--init", "--recursive",
+		),
+	} {
+		if _, err := RunCommand(logger, WithDir(cmd, dir)); err != nil {
+			return err
+		}
+	}
+
+	rawPaths, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`,
+	), dir))
+	if err != nil {
+		// all the submodules may have been removed
+		rawPaths = ""
+	}
+	paths := []string{".gitmodules"}
+	for _, line := range strings.Split(rawPaths, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			paths = append(paths, fields[1])
+		}
+	}
+	paths, err = PresentPaths(ctx, logger, dir, paths)
+	if err != nil {
+		return err
+	}
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"add", "--"}, paths...)...,
+	), dir)); err != nil {
+		return err
+	}
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "diff", "--cached", "--quiet",
+	), dir)); err == nil {
+		return nil
+	}
+	if _, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		"git", append([]string{"commit", "--amend", "--no-edit"}, commitArgs...)...,
+	), dir), env...)); err != nil {
+		return err
+	}
+	return nil
+}
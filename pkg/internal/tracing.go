@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/config/secret"
+)
+
+// tracing records the spans of the run when StartTracing has been called, to export them when it stops.
+var tracing struct {
+	lock    sync.Mutex
+	traceID string
+	spans   []*Span
+	// open are the spans that have not ended yet, innermost last, which new spans are nested under
+	open []*Span
+}
+
+// Span times one phase or command of the run. A nil span, as started when not tracing, does nothing.
+type Span struct {
+	name       string
+	spanID     string
+	parentID   string
+	start, end time.Time
+	attributes map[string]string
+	err        error
+}
+
+// StartTracing starts recording spans, under a root span for the whole run named service. The returned function
+// ends the root span and exports every span to the OTLP/HTTP collector at endpoint.
+func StartTracing(endpoint, service string, attributes map[string]string) func() error {
+	tracing.lock.Lock()
+	tracing.traceID = randomID(16)
+	tracing.spans = nil
+	tracing.open = nil
+	tracing.lock.Unlock()
+	root := StartSpan(service, attributes)
+	return func() error {
+		root.End(nil)
+		tracing.lock.Lock()
+		defer tracing.lock.Unlock()
+		traceID, spans := tracing.traceID, tracing.spans
+		tracing.traceID = ""
+		tracing.spans = nil
+		tracing.open = nil
+		return exportSpans(endpoint, service, traceID, spans)
+	}
+}
+
+// StartSpan starts a span nested under the innermost open one, if tracing.
+func StartSpan(name string, attributes map[string]string) *Span {
+	tracing.lock.Lock()
+	defer tracing.lock.Unlock()
+	if tracing.traceID == "" {
+		return nil
+	}
+	span := &Span{
+		name:       name,
+		spanID:     randomID(8),
+		start:      time.Now(),
+		attributes: map[string]string{},
+	}
+	if len(tracing.open) > 0 {
+		span.parentID = tracing.open[len(tracing.open)-1].spanID
+	}
+	for key, value := range attributes {
+		span.attributes[key] = value
+	}
+	tracing.spans = append(tracing.spans, span)
+	tracing.open = append(tracing.open, span)
+	return span
+}
+
+// StartPhase starts a span for a phase of the run, recording the repo the logger is for, if any.
+func StartPhase(logger *logrus.Entry, name string, attributes map[string]string) *Span {
+	span := StartSpan(name, attributes)
+	if repo, ok := logger.Data["repo"]; ok {
+		span.SetAttribute("repo", fmt.Sprint(repo))
+	}
+	return span
+}
+
+// SetAttribute records an attribute on the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	tracing.lock.Lock()
+	defer tracing.lock.Unlock()
+	s.attributes[key] = value
+}
+
+// End ends the span, marking it as failed with err if set.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	tracing.lock.Lock()
+	defer tracing.lock.Unlock()
+	s.end = time.Now()
+	s.err = err
+	for i := len(tracing.open) - 1; i >= 0; i-- {
+		if tracing.open[i] == s {
+			tracing.open = append(tracing.open[:i], tracing.open[i+1:]...)
+			break
+		}
+	}
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	// crypto/rand does not fail on the platforms we run on
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// censorAttribute strips the secrets from a value, as span attributes hold command lines and their output.
+func censorAttribute(value string) string {
+	return string(secret.Censor([]byte(credentialsRegex.ReplaceAllString(value, "${1}REDACTED@"))))
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	var out []otlpAttribute
+	for key, value := range attributes {
+		attribute := otlpAttribute{Key: key}
+		attribute.Value.StringValue = censorAttribute(value)
+		out = append(out, attribute)
+	}
+	return out
+}
+
+// exportSpans sends the spans to the collector at endpoint with the JSON encoding of OTLP/HTTP.
+func exportSpans(endpoint, service, traceID string, spans []*Span) error {
+	type otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	var exported []otlpSpan
+	for _, span := range spans {
+		end := span.end
+		if end.IsZero() {
+			// the run failed before the span ended
+			end = time.Now()
+		}
+		status := otlpStatus{Code: 1}
+		if span.err != nil {
+			status = otlpStatus{Code: 2, Message: censorAttribute(span.err.Error())}
+		}
+		exported = append(exported, otlpSpan{
+			TraceID:           traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentID,
+			Name:              censorAttribute(span.name),
+			Kind:              1,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+			Status:            status,
+		})
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttributes(map[string]string{"service.name": service})},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "github.com/openshift/operator-framework-tooling"},
+				"spans": exported,
+			}},
+		}},
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export spans: %s", resp.Status)
+	}
+	return nil
+}
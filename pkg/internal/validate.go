@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/test-infra/prow/cmd/generic-autobumper/bumper"
+	"k8s.io/test-infra/prow/config/secret"
+)
+
+// Check is the result of validating one aspect of a repository's configuration.
+type Check struct {
+	Repo string
+	Name string
+	Err  error
+}
+
+// CheckGitRepo validates that dir exists and is a git repository.
+func CheckGitRepo(ctx context.Context, logger *logrus.Entry, dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	if _, err := RunCommand(logger, WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", "--git-dir",
+	), dir)); err != nil {
+		return fmt.Errorf("not a git repository: %w", err)
+	}
+	return nil
+}
+
+// CheckRemote validates that the remote is reachable.
+func CheckRemote(ctx context.Context, logger *logrus.Entry, remote string) error {
+	if _, err := RunCommand(logger, exec.CommandContext(ctx,
+		"git", "ls-remote", "--exit-code", remote, "HEAD",
+	)); err != nil {
+		return fmt.Errorf("remote %s is not reachable: %w", remote, err)
+	}
+	return nil
+}
+
+// CheckRemoteBranch validates that the branch exists on the remote.
+func CheckRemoteBranch(ctx context.Context, logger *logrus.Entry, remote, branch string) error {
+	if _, err := RunCommand(logger, exec.CommandContext(ctx,
+		"git", "ls-remote", "--exit-code", "--heads", remote, branch,
+	)); err != nil {
+		return fmt.Errorf("branch %s not found on remote %s: %w", branch, remote, err)
+	}
+	return nil
+}
+
+// CheckGitHubToken validates that the token at tokenPath authenticates as login with the GitHub API, and that a
+// classic token has the scopes needed to push and open pull requests. Fine-grained tokens do not report scopes, so
+// only their authentication is checked.
+func CheckGitHubToken(ctx context.Context, tokenPath, login string) error {
+	if tokenPath == "" {
+		return fmt.Errorf("no token configured with --github-token-path")
+	}
+	if err := secret.Add(tokenPath); err != nil {
+		return fmt.Errorf("failed to load token: %w", err)
+	}
+	token := secret.GetTokenGenerator(tokenPath)()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/user", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token was rejected by the GitHub API: %s", resp.Status)
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return fmt.Errorf("failed to decode the GitHub user: %w", err)
+	}
+	if user.Login != login {
+		return fmt.Errorf("token authenticates as %s, not %s", user.Login, login)
+	}
+	if scopes, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		granted := map[string]bool{}
+		for _, scope := range strings.Split(strings.Join(scopes, ","), ",") {
+			granted[strings.TrimSpace(scope)] = true
+		}
+		if !granted["repo"] && !granted["public_repo"] {
+			return fmt.Errorf("token has scopes %q, but needs repo or public_repo", strings.Join(scopes, ","))
+		}
+	}
+	return nil
+}
+
+// ReportChecks prints a table of the checks, returning an error if any of them failed.
+func ReportChecks(logger *logrus.Logger, checks []Check) error {
+	writer := tabwriter.NewWriter(bumper.HideSecretsWriter{Delegate: os.Stdout, Censor: secret.Censor}, 0, 4, 2, ' ', 0)
+	failed := 0
+	for _, check := range checks {
+		result := "OK"
+		if check.Err != nil {
+			result = "error: " + check.Err.Error()
+			failed++
+		}
+		if _, err := fmt.Fprintln(writer, check.Repo+"\t"+check.Name+"\t"+result); err != nil {
+			logger.WithError(err).Error("failed to write output")
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		logger.WithError(err).Error("failed to flush output")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d configuration checks failed", failed, len(checks))
+	}
+	return nil
+}
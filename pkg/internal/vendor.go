@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// VerifyVendor checks that the vendor directory of the module in dir is consistent: every module listed in
+// vendor/modules.txt must have a checksum in go.sum, and the go command must accept the vendored tree for the
+// module's packages. `go mod verify` only checks the module cache, so it does not catch a broken vendor directory.
+// With build, the module is also built from the vendored tree. Modules without a vendor directory are skipped.
+func VerifyVendor(ctx context.Context, logger *logrus.Entry, goBin, dir string, env []string, build bool) error {
+	modulesTxt, err := os.Open(filepath.Join(dir, "vendor", "modules.txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.WithField("dir", dir).Debug("no vendor directory, skipping verification")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer modulesTxt.Close()
+
+	rawSums, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	sums := map[string]bool{}
+	for _, line := range strings.Split(string(rawSums), "\n") {
+		// each line is formatted as `<module> <version>[/go.mod] <hash>`
+		if fields := strings.Fields(line); len(fields) == 3 {
+			sums[fields[0]+" "+strings.TrimSuffix(fields[1], "/go.mod")] = true
+		}
+	}
+
+	var missing []string
+	scanner := bufio.NewScanner(modulesTxt)
+	for scanner.Scan() {
+		// modules are listed as `# <module> <version>`, or `# <module> [<version>] => <replacement> [<version>]`
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		module := strings.TrimPrefix(line, "# ")
+		if _, replacement, ok := strings.Cut(module, " => "); ok {
+			module = replacement
+		}
+		fields := strings.Fields(module)
+		if len(fields) != 2 {
+			// replacements with local directories have no version, and so no checksum
+			continue
+		}
+		if !sums[fields[0]+" "+fields[1]] {
+			missing = append(missing, fields[0]+"@"+fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read vendor/modules.txt: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("vendor/modules.txt in %s lists modules without a checksum in go.sum: %s", dir, strings.Join(missing, ", "))
+	}
+
+	// the go command fails on a vendor directory that is inconsistent with go.mod or lacks packages
+	args := []string{"list", "-mod=vendor", "-deps", "./..."}
+	if build {
+		args = []string{"build", "-mod=vendor", "./..."}
+	}
+	if _, err := RunCommand(logger, WithEnv(WithDir(exec.CommandContext(ctx,
+		goBin, args...,
+	), dir), env...)); err != nil {
+		return fmt.Errorf("vendored tree in %s is broken: %w", dir, err)
+	}
+	return nil
+}
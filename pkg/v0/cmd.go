@@ -2,7 +2,6 @@ package v0
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -10,23 +9,48 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	semver "github.com/Masterminds/semver/v3"
 	"github.com/openshift/operator-framework-tooling/pkg/flags"
 	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/openshift/operator-framework-tooling/pkg/internal/jira"
 	"github.com/sirupsen/logrus"
 	"k8s.io/test-infra/prow/cmd/generic-autobumper/bumper"
 	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/labels"
 )
 
 const (
 	githubRepo = "operator-framework-olm"
+
+	// commitOrderDate lists each repo's commits by committer date, which can be non-monotonic after rebases and
+	// may then cherry-pick a commit before one it depends on.
+	commitOrderDate = "date"
+	// commitOrderTopo lists each repo's commits in topological order, so ancestry within a repo is always preserved.
+	// Commits from different repos are still intertwined by committer date, so cross-repo ordering is equally
+	// approximate in both modes.
+	commitOrderTopo = "topo"
+)
+
+const (
+	// depSyncToGoMod synchronizes each dependency repository up to the version OLM requires in its go.mod.
+	depSyncToGoMod = "gomod"
+	// depSyncToTag synchronizes each dependency repository up to its newest release tag, which may be ahead of the
+	// version OLM requires.
+	depSyncToTag = "tag"
 )
 
+// manifestFiles are the files updated by generating manifests.
+var manifestFiles = []string{"manifests", "microshift-manifests", "pkg/manifests"}
+
 var depRepos = []string{
 	"operator-framework/api",
 	"operator-framework/operator-registry",
@@ -37,8 +61,11 @@ func DefaultOptions() Options {
 		stagingDir: "staging/",
 		centralRef: "origin/master",
 		history:    1,
+		keepEmpty:  true,
 		Options:    flags.DefaultOptions(),
 	}
+	opts.commitOrder = commitOrderDate
+	opts.depSyncTo = depSyncToGoMod
 	opts.Options.GithubRepo = githubRepo
 	opts.Options.DelayManifestGeneration = true
 	return opts
@@ -47,14 +74,43 @@ func DefaultOptions() Options {
 type Options struct {
 	flags.Options
 
-	stagingDir string
-	centralRef string
-	history    int
+	stagingDir    string
+	centralRef    string
+	centralRemote string
+	history       int
+	explain       bool
+	keepEmpty     bool
+	commitOrder   string
+	depSyncTo     string
+
+	printPullRequestComment bool
+
+	upstreamBranchOverrides flagutil.Strings
+	upstreamBranches        map[string]string
+
+	commitRangeOverrides flagutil.Strings
+	commitRanges         map[string]string
+
+	stagingPathOverrides flagutil.Strings
+	stagingPaths         map[string]string
+
+	extraRemoteFlags flagutil.Strings
+	extraRemotes     map[string]string
 }
 
 func (o *Options) Bind(fs *flag.FlagSet) {
 	fs.StringVar(&o.stagingDir, "staging-dir", o.stagingDir, "Directory for staging repositories.")
 	fs.StringVar(&o.centralRef, "central-ref", o.centralRef, "Git ref for the central branch that will be updated, used as the base for determining what commits need to be cherry-picked.")
+	fs.BoolVar(&o.explain, "explain", o.explain, "Print the order in which commits from the upstream repositories were intertwined.")
+	fs.StringVar(&o.centralRemote, "central-remote", o.centralRemote, "Git remote to fetch --central-ref from if it is not available locally. If not specified, the downstream repository is used, respecting --fetch-mode.")
+	fs.BoolVar(&o.printPullRequestComment, "print-pull-request-comment", o.printPullRequestComment, "During summarize and synchronize modes, print out the pull request comment (for pasting into a PR).")
+	fs.BoolVar(&o.keepEmpty, "keep-empty", o.keepEmpty, "Keep upstream commits that are empty once cherry-picked. If false, commits whose changes are already present downstream are skipped.")
+	fs.StringVar(&o.commitOrder, "commit-order", o.commitOrder, fmt.Sprintf("Order to list each upstream repository's commits in before intertwining them by date. One of %v. Topological order preserves ancestry within a repository when committer dates are not monotonic.", []string{commitOrderDate, commitOrderTopo}))
+	fs.StringVar(&o.depSyncTo, "dep-sync-to", o.depSyncTo, fmt.Sprintf("What to synchronize the dependency repositories up to. One of %v: the version in OLM's go.mod, or the newest release tag.", []string{depSyncToGoMod, depSyncToTag}))
+	fs.Var(&o.upstreamBranchOverrides, "upstream-branch-overrides", "Upstream branch to track for a staging repository instead of master or the version in go.mod, as repo=branch. May be repeated.")
+	fs.Var(&o.stagingPathOverrides, "staging-path", "Staging directory of a repository that is not laid out as <staging-dir>/<repo>, as repo=path. May be repeated.")
+	fs.Var(&o.extraRemoteFlags, "extra-remote", "Additional remote to fetch a staging repository's refs from when they cannot be fetched from operator-framework, e.g. a fork holding an urgent fix that has not merged yet, as repo=url. Commits that are not upstream are logged. May be repeated.")
+	fs.Var(&o.commitRangeOverrides, "commit-range", "Explicit range of upstream commits to cherry-pick for a staging repository instead of detecting them, as repo=A..B. May be repeated.")
 	fs.IntVar(&o.history, "history", o.history, "How many commits back to start searching for missing vendor commits.")
 
 	o.Options.Bind(fs)
@@ -64,19 +120,114 @@ func (o *Options) Validate() error {
 	if err := o.Options.Validate(); err != nil {
 		return err
 	}
+	if mode := flags.Mode(o.Mode); mode == flags.CherryPickOne || mode == flags.RewriteGoMod {
+		return fmt.Errorf("--mode=%s is only supported for v1", mode)
+	}
+
+	o.upstreamBranches = map[string]string{}
+	for _, override := range o.upstreamBranchOverrides.Strings() {
+		name, branch, ok := strings.Cut(override, "=")
+		if !ok || branch == "" {
+			return fmt.Errorf("--upstream-branch-overrides must be in the form repo=branch, got %q", override)
+		}
+		repo := "operator-framework/" + name
+		if repo != "operator-framework/operator-lifecycle-manager" && !slices.Contains(depRepos, repo) {
+			return fmt.Errorf("--upstream-branch-overrides: unknown repo %q", name)
+		}
+		o.upstreamBranches[repo] = branch
+	}
+
+	o.commitRanges = map[string]string{}
+	for _, override := range o.commitRangeOverrides.Strings() {
+		name, commitRange, ok := strings.Cut(override, "=")
+		if start, end, isRange := strings.Cut(commitRange, ".."); !ok || !isRange || start == "" || end == "" || strings.HasPrefix(end, ".") {
+			return fmt.Errorf("--commit-range must be in the form repo=A..B, got %q", override)
+		}
+		repo := "operator-framework/" + name
+		if repo != "operator-framework/operator-lifecycle-manager" && !slices.Contains(depRepos, repo) {
+			return fmt.Errorf("--commit-range: unknown repo %q", name)
+		}
+		o.commitRanges[name] = commitRange
+	}
+
+	o.stagingPaths = map[string]string{}
+	for _, override := range o.stagingPathOverrides.Strings() {
+		name, path, ok := strings.Cut(override, "=")
+		if !ok || path == "" {
+			return fmt.Errorf("--staging-path must be in the form repo=path, got %q", override)
+		}
+		if !slices.Contains(depRepos, "operator-framework/"+name) {
+			return fmt.Errorf("--staging-path: unknown staging repo %q", name)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("--staging-path: %s: %w", override, err)
+		}
+		o.stagingPaths[name] = filepath.Clean(path)
+	}
+
+	o.extraRemotes = map[string]string{}
+	for _, override := range o.extraRemoteFlags.Strings() {
+		name, remote, ok := strings.Cut(override, "=")
+		if !ok || remote == "" {
+			return fmt.Errorf("--extra-remote must be in the form repo=url, got %q", override)
+		}
+		if !slices.Contains(depRepos, "operator-framework/"+name) {
+			return fmt.Errorf("--extra-remote: unknown staging repo %q", name)
+		}
+		o.extraRemotes["operator-framework/"+name] = remote
+	}
+
+	switch o.depSyncTo {
+	case depSyncToGoMod, depSyncToTag:
+	default:
+		return fmt.Errorf("--dep-sync-to must be one of %v", []string{depSyncToGoMod, depSyncToTag})
+	}
+
+	switch o.commitOrder {
+	case commitOrderDate, commitOrderTopo:
+	default:
+		return fmt.Errorf("--commit-order must be one of %v", []string{commitOrderDate, commitOrderTopo})
+	}
 
 	return nil
 }
 
-func resolveCentralRef(ctx context.Context, logger *logrus.Entry, origCentralRef string) (string, error) {
+// stagingPath returns the staging directory of repo, which is <staging-dir>/<repo> unless overridden by --staging-path.
+func (o *Options) stagingPath(repo string) string {
+	if path, ok := o.stagingPaths[repo]; ok {
+		return path
+	}
+	return filepath.Join(o.stagingDir, repo)
+}
+
+func resolveCentralRef(ctx context.Context, logger *logrus.Entry, origCentralRef string, opts Options) (string, error) {
+	ref := origCentralRef
+	if !internal.RefExists(ctx, logger, ".", ref) {
+		// the ref may only exist on the remote, so fetch it before giving up
+		remote := centralRemote(opts)
+		branch := origCentralRef
+		if remoteName, rest, found := strings.Cut(origCentralRef, "/"); found && isRemote(ctx, logger, remoteName) {
+			branch = rest
+		}
+		logger.WithFields(logrus.Fields{"central-ref": origCentralRef, "remote": remote}).Info("central-ref not found locally, fetching")
+		if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+			"git", "fetch", remote, branch,
+		)); err != nil {
+			if defaultBranch, err := internal.DefaultBranch(ctx, logger, "."); err == nil {
+				logger.WithField("central-ref", origCentralRef).Warnf("central-ref not found, the default branch of origin is %q: consider --central-ref=origin/%s", defaultBranch, defaultBranch)
+			}
+			return "", fmt.Errorf("central-ref %q not found locally or as %q on %s: %w", origCentralRef, branch, remote, err)
+		}
+		ref = "FETCH_HEAD"
+	}
 	output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
 		"git", "log",
 		"-n", "1",
 		"--pretty=%H",
-		origCentralRef,
+		ref,
 	))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("central-ref %q could not be resolved: %w", origCentralRef, err)
 	}
 	newCentralRef := strings.TrimSpace(output)
 	if newCentralRef == "" {
@@ -86,76 +237,217 @@ func resolveCentralRef(ctx context.Context, logger *logrus.Entry, origCentralRef
 	return newCentralRef, nil
 }
 
+// isRemote determines if name is a configured git remote, or origin, which is assumed to be the downstream repository
+// even when it has not been configured.
+func isRemote(ctx context.Context, logger *logrus.Entry, name string) bool {
+	if name == "origin" {
+		return true
+	}
+	_, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+		"git", "config", "--get", "remote."+name+".url",
+	))
+	return err == nil
+}
+
+// centralRemote determines the remote to fetch the central ref from, if it is not available locally.
+func centralRemote(opts Options) string {
+	if opts.centralRemote != "" {
+		return opts.centralRemote
+	}
+	return upstreamRemote(opts.GithubOrg+"/"+opts.GithubRepo, opts)
+}
+
 func Run(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	// commits are looked up repeatedly during a run, but the cache must not outlive it
+	ctx = internal.WithInfoCache(ctx)
+	if flags.Mode(opts.Mode) == flags.ValidateConfig {
+		return internal.WithExitCode(internal.ExitConfig, validateConfig(ctx, logger, opts))
+	}
+	if flags.Mode(opts.Mode) == flags.Doctor {
+		return doctor(ctx, logger, opts)
+	}
+
+	if opts.Mutates() {
+		unlock, err := internal.LockRepo(ctx, logger.WithField("phase", "lock"), ".")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	if opts.DumpScript != "" {
+		stopRecording, err := internal.RecordScript(opts.DumpScript)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := stopRecording(); err != nil {
+				logger.WithError(err).Error("failed to write script")
+			}
+		}()
+	}
+
+	if opts.OTLPEndpoint != "" {
+		stopTracing := internal.StartTracing(opts.OTLPEndpoint, "operator-framework-tooling-v0", map[string]string{"mode": opts.Mode})
+		defer func() {
+			if err := stopTracing(); err != nil {
+				logger.WithError(err).Warn("failed to export traces")
+			}
+		}()
+	}
+
+	if opts.GoBin != "" {
+		if err := internal.LogGoVersion(ctx, logger.WithField("phase", "setup"), opts.Go(), opts.GoEnv()); err != nil {
+			return err
+		}
+	}
+
+	bodyTemplate, err := opts.ParseBodyTemplate()
+	if err != nil {
+		return internal.WithExitCode(internal.ExitConfig, fmt.Errorf("failed to parse body template: %w", err))
+	}
+
 	var commits []internal.Commit
+	var targets map[string]string
+	fetcher := internal.NewFetcher(opts.BatchFetch, opts.GitFetchArgs())
 	if opts.CommitFileInput != "" {
-		rawCommits, err := os.ReadFile(opts.CommitFileInput)
+		metadata, err := internal.ReadPlan(opts.CommitFileInput, &commits)
 		if err != nil {
-			return fmt.Errorf("could not read input file: %w", err)
+			return err
 		}
-		if err := json.Unmarshal(rawCommits, &commits); err != nil {
-			return fmt.Errorf("could not unmarshal input commits: %w", err)
+		if metadata != nil {
+			_, targets, err = resolveTargets(ctx, logger.WithField("phase", "calculate refs"), opts, fetcher)
+			if err != nil {
+				return err
+			}
+		}
+		if err := internal.CheckPlan(logger.WithField("phase", "check plan"), metadata, targets, opts.StrictPlan); err != nil {
+			return err
 		}
 	} else {
 		// if opts.centralRef is modified (i.e. FETCH_HEAD), calculateRepoRefs is going to mess up that calculation,
 		// so resolve opts.centralRef first
-		centralRef, err := resolveCentralRef(ctx, logger.WithField("phase", "resolve central-ref"), opts.centralRef)
+		centralRef, err := resolveCentralRef(ctx, logger.WithField("phase", "resolve central-ref"), opts.centralRef, opts)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to resolve central-ref")
+			return fmt.Errorf("failed to resolve central-ref: %w", err)
 		}
-		repoRefs, err := calculateRepoRefs(ctx, logger.WithField("phase", "calculate refs"), opts)
+		var repoRefs map[string]string
+		repoRefs, targets, err = resolveTargets(ctx, logger.WithField("phase", "calculate refs"), opts, fetcher)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to determine repository references")
+			return err
 		}
-		commits, err = detectNewCommits(ctx, logger.WithField("phase", "detect"), opts.stagingDir, centralRef, repoRefs, flags.FetchMode(opts.FetchMode), opts.history)
+		span := internal.StartPhase(logger.WithField("phase", "detect"), "detect", nil)
+		commits, err = detectNewCommits(ctx, logger.WithField("phase", "detect"), opts.stagingDir, centralRef, repoRefs, opts, opts.history, fetcher)
+		span.End(err)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to detect commits")
+			return fmt.Errorf("failed to detect commits: %w", err)
 		}
 	}
 
-	// Get the tools for the repository
-	if err := internal.RunBingo(ctx, logger.WithField("phase", "bingo")); err != nil {
-		logger.WithError(err).Fatal("failed to setup tools via bingo")
+	missingCommits, err := filterMissingCommits(ctx, logger.WithField("phase", "detect"), opts, commits)
+	if err != nil {
+		return err
 	}
 
-	var missingCommits []internal.Commit
-	for _, commit := range commits {
-		commitLogger := logger.WithField("commit", commit.Hash)
-		missing, err := isCommitMissing(ctx, commitLogger, opts.stagingDir, commit)
-		if err != nil {
-			commitLogger.WithError(err).Fatal("failed to determine if commit is missing")
+	if opts.GithubOutputFile != "" {
+		if err := internal.WriteGitHubOutputs(opts.GithubOutputFile, map[string]string{
+			"target_sha":  targets["operator-framework/operator-lifecycle-manager"],
+			"carry_count": strconv.Itoa(len(missingCommits)),
+			"changed":     strconv.FormatBool(len(missingCommits) > 0),
+		}); err != nil {
+			return err
 		}
-		if missing {
-			missingCommits = append(missingCommits, commit)
+	}
+
+	if flags.Mode(opts.Mode) == flags.Lag {
+		return printLag(ctx, logger, opts, missingCommits)
+	}
+
+	if opts.RequireSignedUpstream {
+		verifier := opts.SignatureVerifier()
+		for _, repo := range append([]string{"operator-framework/operator-lifecycle-manager"}, depRepos...) {
+			if targets[repo] == "" {
+				// plans written without metadata do not resolve the targets
+				return fmt.Errorf("%s: no upstream target to verify, --require-signed-upstream needs a plan written with targets", repo)
+			}
+			if err := verifier.Verify(ctx, logger.WithField("repo", repo), "", targets[repo]); err != nil {
+				return fmt.Errorf("%s: refusing to synchronize to an unverified upstream target: %w", repo, err)
+			}
+		}
+		if opts.RequireSignedCarries {
+			for _, commit := range missingCommits {
+				if err := verifier.Verify(ctx, logger.WithField("repo", commit.Repo), "", commit.Hash); err != nil {
+					return fmt.Errorf("refusing to cherry-pick an unverified commit: %w", err)
+				}
+			}
 		}
 	}
 
 	if opts.CommitFileOutput != "" {
-		commitsJson, err := json.Marshal(missingCommits)
-		if err != nil {
-			return fmt.Errorf("could not marshal commits: %w", err)
-		}
-		if err := os.WriteFile(opts.CommitFileOutput, commitsJson, 0666); err != nil {
-			return fmt.Errorf("could not write commits: %w", err)
+		if err := internal.WritePlan(opts.CommitFileOutput, internal.NewPlanMetadata(targets), missingCommits); err != nil {
+			return err
 		}
 	}
 
-	cherryPickAll := func() {
+	if opts.DetectOnly {
+		logger.WithField("commits", len(missingCommits)).Info("detected commits, exiting")
+		return nil
+	}
+
+	// Get the tools for the repository
+	if err := internal.RunBingo(ctx, logger.WithField("phase", "bingo")); err != nil {
+		return fmt.Errorf("failed to setup tools via bingo: %w", err)
+	}
+
+	cherryPickAll := func() error {
 		if err := internal.SetCommitter(ctx, logger.WithField("phase", "setup"), opts.GitName, opts.GitEmail); err != nil {
-			logger.WithError(err).Fatal("failed to set committer")
+			return fmt.Errorf("failed to set committer: %w", err)
 		}
+		if opts.ResetDates {
+			date := time.Now()
+			logger.WithField("phase", "setup").WithField("date", date.Format(time.RFC3339)).Info("setting the committer date of new commits")
+			opts.PinCommitterDate(date)
+		}
+		if err := internal.ApplyGitConfig(ctx, logger.WithField("phase", "setup"), "", opts.GitConfig.Strings()); err != nil {
+			return err
+		}
+		picked := false
 		for i, commit := range missingCommits {
 			commitLogger := logger.WithField("commit", commit.Hash).WithField("repo", commit.Repo)
 			commitLogger.Infof("cherry-picking commit %d/%d", i+1, len(missingCommits))
 			delay := opts.DelayManifestGeneration
-			if i+1 == len(missingCommits) {
+			if i+1 == len(missingCommits) && opts.keepEmpty {
 				// we are on the last commit, we need to run the delayed commands
 				delay = false
 			}
-			if err := cherryPick(ctx, commitLogger, commit, opts.GitCommitArgs(), delay); err != nil {
-				logger.WithError(err).Fatal("failed to cherry-pick commit")
+			span := internal.StartPhase(commitLogger, "cherry-pick", map[string]string{"repo": commit.Repo, "commit": commit.Hash})
+			ok, err := cherryPick(ctx, commitLogger, commit, opts, delay)
+			span.End(err)
+			if err != nil {
+				return fmt.Errorf("failed to cherry-pick commit: %w", err)
+			}
+			picked = picked || ok
+		}
+		if opts.DelayManifestGeneration && !opts.keepEmpty && picked {
+			// the last commit may have been skipped, so the delayed commands amend the last one that was picked
+			if err := generateManifests(ctx, logger.WithField("phase", "manifests"), opts.GitCarryCommitArgs(), opts.GitEnv()); err != nil {
+				return fmt.Errorf("failed to generate manifests: %w", err)
+			}
+		}
+		if !opts.NoVendor {
+			moduleDirs := []string{""}
+			for _, commit := range missingCommits {
+				if !slices.Contains(moduleDirs, opts.stagingPath(commit.Repo)) {
+					moduleDirs = append(moduleDirs, opts.stagingPath(commit.Repo))
+				}
+			}
+			for _, dir := range moduleDirs {
+				if err := internal.VerifyVendor(ctx, logger.WithField("phase", "verify vendor"), opts.Go(), dir, opts.GoEnv(), opts.VerifyBuild); err != nil {
+					return err
+				}
 			}
 		}
+		return nil
 	}
 
 	if len(missingCommits) == 0 {
@@ -166,10 +458,24 @@ func Run(ctx context.Context, logger *logrus.Logger, opts Options) error {
 	switch flags.Mode(opts.Mode) {
 	case flags.Summarize:
 		internal.Table(logger, missingCommits, "operator-framework/")
+		if opts.printPullRequestComment {
+			if err := printPullRequestComment(ctx, logger, opts, bodyTemplate, commits); err != nil {
+				return err
+			}
+		}
 	case flags.Synchronize:
-		cherryPickAll()
+		if err := cherryPickAll(); err != nil {
+			return err
+		}
+		if opts.printPullRequestComment {
+			if err := printPullRequestComment(ctx, logger, opts, bodyTemplate, commits); err != nil {
+				return err
+			}
+		}
 	case flags.Publish:
-		cherryPickAll()
+		if err := cherryPickAll(); err != nil {
+			return err
+		}
 		gc, err := opts.GitHubOptions.GitHubClient(opts.DryRun)
 		if err != nil {
 			return fmt.Errorf("error getting GitHub client: %w", err)
@@ -180,82 +486,306 @@ func Run(ctx context.Context, logger *logrus.Logger, opts Options) error {
 		stderr := bumper.HideSecretsWriter{Delegate: os.Stderr, Censor: secret.Censor}
 
 		remoteBranch := "synchronize-upstream"
-		title := "NO-ISSUE: Synchronize From Upstream Repositories"
-		if err := bumper.MinimalGitPush(fmt.Sprintf("https://%s:%s@github.com/%s/%s.git", opts.GithubLogin,
-			string(secret.GetTokenGenerator(opts.GitHubOptions.TokenPath)()), opts.GithubLogin, opts.GithubRepo),
-			remoteBranch, stdout, stderr, opts.DryRun); err != nil {
+		title := opts.PRTitle()
+		span := internal.StartPhase(logger.WithField("phase", "push"), "push", nil)
+		remote := fmt.Sprintf("https://%s:%s@github.com/%s/%s.git", opts.GithubLogin,
+			string(secret.GetTokenGenerator(opts.GitHubOptions.TokenPath)()), opts.GithubLogin, opts.GithubRepo)
+		err = internal.PushForkBranch(ctx, logger.WithField("phase", "push"), "", remote, remoteBranch, opts.centralRef, opts.ResetFork, opts.NoForcePush, stdout, stderr, opts.DryRun)
+		span.End(err)
+		if err != nil {
 			return fmt.Errorf("Failed to push changes.: %w", err)
 		}
 
+		changes, err := internal.GoModChanges(ctx, logger.WithField("phase", "dependencies"), opts.Go(), ".", opts.centralRef, opts.GoEnv())
+		if err != nil {
+			logger.WithError(err).Warn("failed to determine go.mod changes")
+		}
+
+		body, err := internal.GetBody(bodyTemplate, commits, changes, opts.GetDiffStat(ctx, logger.WithField("phase", "diffstat"), ".", opts.centralRef), assignees(ctx, logger, opts, commits))
+		if err != nil {
+			return err
+		}
+		if opts.ForkOnly {
+			fmt.Printf("Create the pull request at %s\nwith the following body:\n\n%s\n",
+				internal.PullRequestURL(opts.GithubOrg, opts.GithubRepo, opts.PRBaseBranch, opts.GithubLogin+":"+remoteBranch, title), body)
+			return nil
+		}
+
 		var labelsToAdd []string
 		if opts.SelfApprove {
 			logger.Infof("Self-approving PR by adding the %q and %q labels", labels.Approved, labels.LGTM)
 			labelsToAdd = append(labelsToAdd, labels.Approved, labels.LGTM)
 		}
+		var existing *github.Issue
+		if opts.CommentOnUpdate {
+			existing, err = internal.FindSyncPR(gc, opts.GithubOrg, opts.GithubRepo, remoteBranch)
+			if err != nil {
+				logger.WithError(err).Warn("failed to find existing pull request")
+			}
+		}
 		if err := bumper.UpdatePullRequestWithLabels(gc, opts.GithubOrg, opts.GithubRepo, title,
-			internal.GetBody(commits, strings.Split(opts.Assign, ",")), opts.GithubLogin+":"+remoteBranch, opts.PRBaseBranch, remoteBranch, true, labelsToAdd, opts.DryRun); err != nil {
+			body, opts.GithubLogin+":"+remoteBranch, opts.PRBaseBranch, remoteBranch, true, labelsToAdd, opts.DryRun); err != nil {
 			return fmt.Errorf("PR creation failed.: %w", err)
 		}
+		internal.CommentOnUpdate(logger.WithField("phase", "comment"), gc, opts.GithubOrg, opts.GithubRepo, existing, body, opts.DryRun)
+
+		jc, err := opts.JiraClient()
+		if err != nil {
+			logger.WithError(err).Warn("failed to create Jira client, not reporting to Jira")
+		} else if jc != nil {
+			link := internal.PullRequestURL(opts.GithubOrg, opts.GithubRepo, opts.PRBaseBranch, opts.GithubLogin+":"+remoteBranch, title)
+			if pr, err := internal.FindSyncPR(gc, opts.GithubOrg, opts.GithubRepo, remoteBranch); err == nil && pr != nil {
+				link = pr.HTMLURL
+			}
+			jc.Report(ctx, logger.WithField("phase", "jira"), []jira.Sync{{
+				Repo:        opts.GithubRepo,
+				PullRequest: link,
+				Target:      targets["operator-framework/operator-lifecycle-manager"],
+				Carries:     len(commits),
+			}}, opts.JiraTransition, opts.DryRun)
+		}
 	}
 	return nil
 }
 
-func getTagOrCommit(ctx context.Context, repo string, dir string, opts Options, logger *logrus.Entry) (string, error) {
+// assignees returns the users to cc on the pull request: --assign, and with --cc-owners the approvers of the staging
+// paths changed by commits.
+func assignees(ctx context.Context, logger *logrus.Logger, opts Options, commits []internal.Commit) []string {
+	assign := strings.Split(opts.Assign, ",")
+	if !opts.CcOwners {
+		return assign
+	}
+	for _, repo := range depRepos {
+		name := filepath.Base(repo)
+		var repoCommits []internal.Commit
+		for _, commit := range commits {
+			if commit.Repo == name {
+				repoCommits = append(repoCommits, commit)
+			}
+		}
+		for _, owner := range internal.CommitOwners(ctx, logger.WithField("repo", repo), ".", opts.stagingPath(name), repoCommits) {
+			if !slices.Contains(assign, owner) {
+				assign = append(assign, owner)
+			}
+		}
+	}
+	return assign
+}
 
-	// Create temporary
+// printPullRequestComment prints the body the pull request would be created with, for pasting into a PR. Before
+// synchronizing, there are no go.mod changes to list yet.
+func printPullRequestComment(ctx context.Context, logger *logrus.Logger, opts Options, bodyTemplate *template.Template, commits []internal.Commit) error {
+	changes, err := internal.GoModChanges(ctx, logger.WithField("phase", "dependencies"), opts.Go(), ".", opts.centralRef, opts.GoEnv())
+	if err != nil {
+		logger.WithError(err).Warn("failed to determine go.mod changes")
+	}
+	// before synchronizing, there are no changes to count
+	var stat *internal.DiffStat
+	if flags.Mode(opts.Mode) != flags.Summarize {
+		stat = opts.GetDiffStat(ctx, logger.WithField("phase", "diffstat"), ".", opts.centralRef)
+	}
+	body, err := internal.GetBody(bodyTemplate, commits, changes, stat, assignees(ctx, logger, opts, commits))
+	if err != nil {
+		return err
+	}
+	fmt.Println(body)
+	if opts.SelfApprove {
+		fmt.Printf("/label %s\n", labels.Approved)
+		fmt.Printf("/label %s\n", labels.LGTM)
+	}
+	return nil
+}
 
-	module := fmt.Sprintf("github.com/%s", repo)
-	rawInfo, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"go", "list", "-json", "-m", module), dir))
+// printLag prints how many upstream commits and how much time each staging repo is behind, measured from the
+// parent of its oldest missing commit to its newest one.
+func printLag(ctx context.Context, logger *logrus.Logger, opts Options, missingCommits []internal.Commit) error {
+	var lags []internal.Lag
+	for _, repo := range depRepos {
+		lag := internal.Lag{Repo: filepath.Base(repo)}
+		var oldest, newest *internal.Commit
+		for i, commit := range missingCommits {
+			if commit.Repo != lag.Repo {
+				continue
+			}
+			lag.Commits++
+			if oldest == nil || commit.Date.Before(oldest.Date) {
+				oldest = &missingCommits[i]
+			}
+			if newest == nil || commit.Date.After(newest.Date) {
+				newest = &missingCommits[i]
+			}
+		}
+		if oldest != nil {
+			synchronized, err := internal.Info(ctx, logger.WithField("repo", repo), oldest.Hash+"^", ".")
+			if err != nil {
+				return fmt.Errorf("failed to determine commit info: %w", err)
+			}
+			lag.Behind = newest.Date.Sub(synchronized.Date)
+		}
+		lags = append(lags, lag)
+	}
+	return internal.PrintLag(os.Stdout, lags, opts.LagJSON)
+}
+
+// validateConfig checks that the downstream and upstream repositories are configured correctly, without modifying anything.
+func validateConfig(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	var checks []internal.Check
+	checks = append(checks, internal.Check{Repo: opts.GithubRepo, Name: "git repository", Err: internal.CheckGitRepo(ctx, logger.WithField("repo", opts.GithubRepo), ".")})
+	if !opts.Offline {
+		checks = append(checks, internal.Check{Repo: opts.GithubRepo, Name: "remote " + centralRemote(opts), Err: internal.CheckRemote(ctx, logger.WithField("repo", opts.GithubRepo), centralRemote(opts))})
+	}
+
+	modules, err := internal.LoadModuleCache("")
 	if err != nil {
-		return "", fmt.Errorf("failed to determine dependent version for module %s: %w", module, err)
+		return err
 	}
-	var info struct {
-		Version string `json:"Version"`
+	for _, repo := range append([]string{"operator-framework/operator-lifecycle-manager"}, depRepos...) {
+		repoLogger := logger.WithField("repo", repo)
+		dir := opts.stagingPath(filepath.Base(repo))
+		_, err := os.Stat(dir)
+		checks = append(checks, internal.Check{Repo: repo, Name: "staging directory " + dir, Err: err})
+		if repo != "operator-framework/operator-lifecycle-manager" {
+			module := "github.com/" + repo
+			_, err := modules.Version(ctx, repoLogger, opts.Go(), ".", "", module, opts.GoEnv())
+			checks = append(checks, internal.Check{Repo: repo, Name: "module " + module, Err: err})
+		}
+		if !opts.Offline {
+			checks = append(checks, internal.Check{Repo: repo, Name: "remote " + upstreamRemote(repo, opts), Err: internal.CheckRemote(ctx, repoLogger, upstreamRemote(repo, opts))})
+			if branch, ok := opts.upstreamBranches[repo]; ok {
+				checks = append(checks, internal.Check{Repo: repo, Name: "upstream branch " + branch, Err: internal.CheckRemoteBranch(ctx, repoLogger, upstreamRemote(repo, opts), branch)})
+			}
+		}
+	}
+	return internal.ReportChecks(logger, checks)
+}
+
+// doctor checks that the remotes are reachable with the configured fetch mode, and that the GitHub token used to
+// publish is valid, without modifying anything.
+func doctor(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	checks := []internal.Check{
+		{Repo: opts.GithubRepo, Name: "remote " + centralRemote(opts), Err: internal.CheckRemote(ctx, logger.WithField("repo", opts.GithubRepo), centralRemote(opts))},
 	}
-	if err := json.Unmarshal([]byte(rawInfo), &info); err != nil {
-		return "", fmt.Errorf("failed to parse module version for %s: %w", module, err)
+	for _, repo := range append([]string{"operator-framework/operator-lifecycle-manager"}, depRepos...) {
+		checks = append(checks, internal.Check{Repo: repo, Name: "remote " + upstreamRemote(repo, opts), Err: internal.CheckRemote(ctx, logger.WithField("repo", repo), upstreamRemote(repo, opts))})
 	}
-	logger.WithFields(logrus.Fields{"repo": repo, "version": info.Version}).Info("resolved latest version")
+	checks = append(checks, internal.Check{Repo: opts.GithubLogin + "/" + opts.GithubRepo, Name: "GitHub token", Err: internal.CheckGitHubToken(ctx, opts.GitHubOptions.TokenPath, opts.GithubLogin)})
+	return internal.ReportChecks(logger, checks)
+}
+
+func getTagOrCommit(ctx context.Context, repo, dir, commit string, modules *internal.ModuleCache, logger *logrus.Entry, opts Options) (string, error) {
+
+	// Create temporary
 
-	v, err := semver.NewVersion(info.Version)
+	module := fmt.Sprintf("github.com/%s", repo)
+	version, err := modules.Version(ctx, logger, opts.Go(), dir, commit, module, opts.GoEnv())
+	if err != nil {
+		return "", err
+	}
+	logger.WithFields(logrus.Fields{"repo": repo, "version": version}).Info("resolved latest version")
+
+	v, err := semver.NewVersion(version)
 	if err != nil {
 		return "", err
 	}
 	// If this does not have a Prerelease, then we just return the version string
 	pre := v.Prerelease()
 	if pre == "" {
-		return info.Version, nil
+		return version, nil
 	}
 	// It's a pre-release version, which we assume is in DATE-COMMIT format
 	pres := strings.Split(pre, "-")
 	if len(pres) != 2 {
-		return "", fmt.Errorf("Bad prerelease: %q", info.Version)
+		return "", fmt.Errorf("Bad prerelease: %q", version)
 	}
 	// Return the second component, which is a commit SHA
 	return pres[1], nil
 }
 
-func calculateRepoRefs(ctx context.Context, logger *logrus.Entry, opts Options) (map[string]string, error) {
-	repoRefs := map[string]string{}
+// latestTag determines the newest release tag on remote by semantic version. Pre-release tags and tags that are not
+// semantic versions are ignored.
+func latestTag(ctx context.Context, logger *logrus.Entry, remote string) (string, error) {
+	output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+		"git", "ls-remote", "--tags", "--refs", remote,
+	))
+	if err != nil {
+		return "", err
+	}
+	var latest *semver.Version
+	var latestName string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "refs/tags/")
+		v, err := semver.NewVersion(name)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, latestName = v, name
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no release tags found on %s", remote)
+	}
+	logger.WithField("tag", latestName).Info("resolved newest release tag")
+	return latestName, nil
+}
+
+// resolveTargets determines the refs to fetch from each upstream repository, and the commits they resolve to.
+func resolveTargets(ctx context.Context, logger *logrus.Entry, opts Options, fetcher *internal.Fetcher) (map[string]string, map[string]string, error) {
+	modules, err := internal.LoadModuleCache(opts.ModuleCacheFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	repoRefs, err := calculateRepoRefs(ctx, logger, opts, modules, fetcher)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to determine repository references: %w", err)
+	}
+	if err := modules.Save(); err != nil {
+		return nil, nil, err
+	}
 
-	// for operator-lifecycle-manager, always use master
-	repoRefs["operator-framework/operator-lifecycle-manager"] = "master"
+	targets := map[string]string{}
+	for repo, ref := range repoRefs {
+		targets[repo] = ref
+	}
+	// operator-lifecycle-manager tracks a branch rather than a commit
+	olm := "operator-framework/operator-lifecycle-manager"
+	targets[olm], err = fetcher.Resolve(ctx, logger, ".", upstreamRemote(olm, opts), repoRefs[olm])
+	if err != nil {
+		return nil, nil, err
+	}
+	return repoRefs, targets, nil
+}
 
-	// Create a temporary worktree of upstream OLM to figure out what dependency versions we are moving to
-	var remote string
+func calculateRepoRefs(ctx context.Context, logger *logrus.Entry, opts Options, modules *internal.ModuleCache, fetcher *internal.Fetcher) (map[string]string, error) {
+	repoRefs := map[string]string{}
 
-	switch flags.FetchMode(opts.FetchMode) {
-	case flags.SSH:
-		remote = "git@github.com:operator-framework/operator-lifecycle-manager"
-	case flags.HTTPS:
-		remote = "https://github.com/operator-framework/operator-lifecycle-manager.git"
+	for repo, branch := range opts.upstreamBranches {
+		if err := internal.CheckRemoteBranch(ctx, logger.WithField("repo", repo), upstreamRemote(repo, opts), branch); err != nil {
+			extra, ok := opts.extraRemotes[repo]
+			if !ok {
+				return nil, err
+			}
+			if err := internal.CheckRemoteBranch(ctx, logger.WithField("repo", repo), extra, branch); err != nil {
+				return nil, err
+			}
+		}
 	}
-	if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-		"git", "fetch",
-		remote,
-		"master",
-	)); err != nil {
+
+	// for operator-lifecycle-manager, use master unless overridden
+	olmBranch := "master"
+	if branch, ok := opts.upstreamBranches["operator-framework/operator-lifecycle-manager"]; ok {
+		olmBranch = branch
+	}
+	repoRefs["operator-framework/operator-lifecycle-manager"] = olmBranch
+
+	// Create a temporary worktree of upstream OLM to figure out what dependency versions we are moving to
+	remote := upstreamRemote("operator-framework/operator-lifecycle-manager", opts)
+	olmRef, err := fetcher.Fetch(ctx, logger, ".", remote, olmBranch)
+	if err != nil {
 		return nil, err
 	}
 	dir, err := os.MkdirTemp("", "olm")
@@ -267,30 +797,41 @@ func calculateRepoRefs(ctx context.Context, logger *logrus.Entry, opts Options)
 		"git", "worktree",
 		"add",
 		dir,
-		"FETCH_HEAD",
+		olmRef,
 	)); err != nil {
 		return nil, err
 	}
+	// the worktree is in a new temporary directory every run, so the module versions are cached by the OLM commit
+	olmCommit, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+		"git", "rev-parse", olmRef+"^{commit}",
+	))
+	if err != nil {
+		return nil, err
+	}
+	olmCommit = strings.TrimSpace(olmCommit)
 
 	for _, repo := range depRepos {
-		tag, err := getTagOrCommit(ctx, repo, dir, opts, logger.WithField("phase", "version scan"))
-		if err != nil {
-			logger.Fatalf("Error processing version for %q: %v", repo, err)
-			continue
+		tag, overridden := opts.upstreamBranches[repo]
+		if overridden {
+			logger.WithFields(logrus.Fields{"repo": repo, "branch": tag}).Info("tracking upstream branch instead of go.mod version")
+		} else if opts.depSyncTo == depSyncToTag {
+			tag, err = latestTag(ctx, logger.WithField("repo", repo), upstreamRemote(repo, opts))
+			if err != nil {
+				return nil, fmt.Errorf("error finding the newest tag for %q: %w", repo, err)
+			}
+		} else {
+			tag, err = getTagOrCommit(ctx, repo, dir, olmCommit, modules, logger.WithField("phase", "version scan"), opts)
+			if err != nil {
+				return nil, fmt.Errorf("error processing version for %q: %w", repo, err)
+			}
 		}
 
-		var remote string
-		switch flags.FetchMode(opts.FetchMode) {
-		case flags.SSH:
-			remote = "git@github.com:" + repo
-		case flags.HTTPS:
-			remote = "https://github.com/" + repo + ".git"
-		}
-		if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-			"git", "fetch",
-			remote,
-			tag,
-		)); err != nil {
+		remote := upstreamRemote(repo, opts)
+		// master is needed when downstream has moved beyond the tag, so fetch it along with the tag when batching, or
+		// along with the next ref whose history is needed when the tag is fetched shallow
+		fetcher.Add(".", remote, "master")
+		tagRef, err := fetchUpstream(ctx, logger.WithField("repo", repo), fetcher, repo, tag, true, opts)
+		if err != nil {
 			return nil, err
 		}
 		output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
@@ -298,7 +839,7 @@ func calculateRepoRefs(ctx context.Context, logger *logrus.Entry, opts Options)
 			"-n", "1",
 			"--pretty=%H",
 			"--no-merges",
-			"FETCH_HEAD",
+			tagRef,
 		))
 		if err != nil {
 			return nil, err
@@ -318,34 +859,83 @@ func calculateRepoRefs(ctx context.Context, logger *logrus.Entry, opts Options)
 	return repoRefs, nil
 }
 
-var commitRegex = regexp.MustCompile(`Upstream-commit: ([a-f0-9]+)\n`)
-
-func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, centralRef string, repoRefs map[string]string, mode flags.FetchMode, history int) ([]internal.Commit, error) {
-	lastCommits := map[string]string{}
-	if err := fs.WalkDir(os.DirFS(stagingDir), ".", func(path string, d fs.DirEntry, err error) error {
+// upstreamRemote determines the remote to fetch the upstream org/name repository from.
+func upstreamRemote(repo string, opts Options) string {
+	mode := flags.FetchMode(opts.FetchMode)
+	switch mode {
+	case flags.SSH:
+		return "git@github.com:" + repo
+	case flags.HTTPS:
+		return "https://github.com/" + repo + ".git"
+	case flags.FILE:
+		path, err := filepath.Abs(opts.FetchDir)
 		if err != nil {
-			return err
-		}
-		if d == nil || !d.IsDir() {
-			return nil
+			panic(fmt.Errorf("Unable to canonicalize %q: %w", opts.FetchDir, err))
 		}
+		return "file://" + path + "/" + filepath.Base(repo)
+	default:
+		panic(fmt.Errorf("unexpected fetch mode %s", mode))
+	}
+}
 
-		if path == "." {
-			return nil
+// fetchUpstream fetches ref of the upstream org/name repository, without its history if shallow. If it cannot be,
+// and the repository has an --extra-remote, ref is fetched from there instead.
+func fetchUpstream(ctx context.Context, logger *logrus.Entry, fetcher *internal.Fetcher, repo, ref string, shallow bool, opts Options) (string, error) {
+	fetch := fetcher.Fetch
+	if shallow {
+		fetch = fetcher.FetchShallow
+	}
+	fetched, err := fetch(ctx, logger, ".", upstreamRemote(repo, opts), ref)
+	extra, ok := opts.extraRemotes[repo]
+	if err == nil || !ok {
+		return fetched, err
+	}
+	logger.WithError(err).WithFields(logrus.Fields{"ref": ref, "remote": extra}).Warn("ref not found upstream, fetching it from the non-canonical extra remote")
+	return fetch(ctx, logger, ".", extra, ref)
+}
+
+// logNonCanonicalCommits warns about each of the commits of a repository with an --extra-remote that is not on its
+// upstream master branch, as those come from a non-canonical source.
+func logNonCanonicalCommits(ctx context.Context, logger *logrus.Entry, fetcher *internal.Fetcher, repo string, commits []internal.Commit, opts Options) error {
+	if _, ok := opts.extraRemotes[repo]; !ok {
+		return nil
+	}
+	master, err := fetcher.Fetch(ctx, logger, ".", upstreamRemote(repo, opts), "master")
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+			"git", "merge-base", "--is-ancestor", commit.Hash, master,
+		)); err != nil {
+			logger.WithFields(logrus.Fields{"commit": commit.Hash, "remote": opts.extraRemotes[repo]}).Warn("commit is not upstream, it comes from the non-canonical extra remote")
 		}
-		walkLogger := logger.WithField("repo", path)
+	}
+	return nil
+}
+
+// deletedInHeadRegex matches the paths of cherry-pick conflicts between a deletion in HEAD and a modification in the
+// commit being picked.
+var deletedInHeadRegex = regexp.MustCompile(`CONFLICT \(modify/delete\): (\S+) deleted in HEAD and modified in`)
+
+var commitRegex = regexp.MustCompile(`Upstream-commit: ([a-f0-9]+)\n`)
+
+func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, centralRef string, repoRefs map[string]string, opts Options, history int, fetcher *internal.Fetcher) ([]internal.Commit, error) {
+	lastCommits := map[string]string{}
+	detectLastCommit := func(repo, dir string) error {
+		walkLogger := logger.WithField("repo", repo)
 		walkLogger.Debug("detecting commits")
 		output, err := internal.RunCommand(walkLogger, exec.CommandContext(ctx,
 			"git", "log",
 			centralRef,
 			"-n", strconv.Itoa(history),
-			"--grep", "Upstream-repository: "+path,
+			"--grep", "Upstream-repository: "+repo,
 			"--grep", "Upstream-commit",
 			"--all-match",
 			"--pretty=%B",
 			"--reverse",
 			"--",
-			filepath.Join(stagingDir, path),
+			dir,
 		))
 		if err != nil {
 			return err
@@ -359,28 +949,64 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, cen
 		}
 		if lastCommit != "" {
 			walkLogger.WithField("commit", lastCommit).Debug("found last commit synchronized with staging")
-			lastCommits[path] = lastCommit
+			lastCommits[repo] = lastCommit
 		} else {
-			walkLogger.Fatal("did not find the last commit synchronized with staging")
+			return fmt.Errorf("did not find the last commit synchronized with staging for %s", repo)
+		}
+		return nil
+	}
+	if err := fs.WalkDir(os.DirFS(stagingDir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d == nil || !d.IsDir() {
+			return nil
 		}
 
-		if path != "." {
+		if path == "." {
+			return nil
+		}
+		// repos with an explicit staging path are detected below, as are the directories that only hold those
+		dir := filepath.Join(stagingDir, path)
+		for _, stagingPath := range opts.stagingPaths {
+			if stagingPath == dir || strings.HasPrefix(stagingPath, dir+string(filepath.Separator)) {
+				return fs.SkipDir
+			}
+		}
+		if _, overridden := opts.stagingPaths[path]; overridden {
 			return fs.SkipDir
 		}
-		return nil
+		if err := detectLastCommit(path, dir); err != nil {
+			return err
+		}
+		return fs.SkipDir
 	}); err != nil {
 		return nil, fmt.Errorf("failed to walk %s: %w", stagingDir, err)
 	}
+	for repo, stagingPath := range opts.stagingPaths {
+		if err := detectLastCommit(repo, stagingPath); err != nil {
+			return nil, err
+		}
+	}
 
+	logArgs := []string{"log", "--pretty=%H", "--no-merges"}
+	if opts.commitOrder == commitOrderTopo {
+		logArgs = append(logArgs, "--topo-order")
+	}
 	commits := map[string][]internal.Commit{}
 	for repo, lastCommit := range lastCommits {
 		repoLogger := logger.WithField("repo", repo)
-		var remote string
-		switch mode {
-		case flags.SSH:
-			remote = "git@github.com:operator-framework/" + repo
-		case flags.HTTPS:
-			remote = "https://github.com/operator-framework/" + repo + ".git"
+		remote := upstreamRemote("operator-framework/"+repo, opts)
+
+		if commitRange, ok := opts.commitRanges[repo]; ok {
+			rangeCommits, err := commitsInRange(ctx, repoLogger, repo, commitRange, logArgs, opts, fetcher)
+			if err != nil {
+				return nil, err
+			}
+			if len(rangeCommits) > 0 {
+				commits[repo] = rangeCommits
+			}
+			continue
 		}
 
 		ref, ok := repoRefs["operator-framework/"+repo]
@@ -388,20 +1014,30 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, cen
 			return nil, fmt.Errorf("ref not found for %q", repo)
 		}
 		repoLogger.WithField("ref", ref).Debug("found fetch reference")
-		if _, err := internal.RunCommand(repoLogger, exec.CommandContext(ctx,
-			"git", "fetch",
-			remote,
-			ref,
-		)); err != nil {
+		fetched, err := fetchUpstream(ctx, repoLogger, fetcher, "operator-framework/"+repo, ref, false, opts)
+		if err != nil {
 			return nil, err
 		}
 
 		output, err := internal.RunCommand(repoLogger, exec.CommandContext(ctx,
-			"git", "log",
-			"--pretty=%H",
-			"--no-merges",
-			lastCommit+"...FETCH_HEAD",
+			"git", append(append(logArgs,
+				lastCommit+"..."+fetched,
+			), opts.PathSpec()...)...,
 		))
+		if err != nil {
+			// A shallow fetch of the tag may have left us without the history needed to compare against the last commit
+			unshallowed, err2 := internal.Unshallow(ctx, repoLogger, ".", remote, ref)
+			if err2 != nil {
+				return nil, err2
+			}
+			if unshallowed {
+				output, err = internal.RunCommand(repoLogger, exec.CommandContext(ctx,
+					"git", append(append(logArgs,
+						lastCommit+"..."+fetched,
+					), opts.PathSpec()...)...,
+				))
+			}
+		}
 		if err != nil {
 			// This could be due to the lastCommit being beyond the tag, in this case,
 			// we'd see an "Invalid symmetric difference expression" error.
@@ -412,18 +1048,15 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, cen
 				return nil, err
 			}
 			repoLogger.Debug("checking if downtream has moved beyond expected commit")
-			if _, err2 := internal.RunCommand(repoLogger, exec.CommandContext(ctx,
-				"git", "fetch",
-				remote,
-				"master",
-			)); err2 != nil {
+			master, err2 := fetcher.Fetch(ctx, repoLogger, ".", remote, "master")
+			if err2 != nil {
 				return nil, err2
 			}
 			if _, err2 := internal.RunCommand(repoLogger, exec.CommandContext(ctx,
 				"git", "log",
 				"--pretty=%H",
 				"--no-merges",
-				lastCommit+"...FETCH_HEAD",
+				lastCommit+"..."+master,
 			)); err2 != nil {
 				// Still getting an error, so return the original `err`
 				return nil, err
@@ -448,8 +1081,20 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, cen
 				commits[repo] = append(commits[repo], commit)
 			}
 		}
+		if len(opts.PathFilters.Strings()) > 0 && output != "" {
+			var included []string
+			for _, commit := range commits[repo] {
+				included = append(included, commit.Hash)
+			}
+			if err := internal.WarnFilteredDependencies(ctx, repoLogger, ".", []string{lastCommit + "..." + fetched}, included); err != nil {
+				return nil, err
+			}
+		}
 		if len(commits[repo]) > 0 {
 			repoLogger.WithField("commits", len(commits[repo])).Debug("found commits")
+			if err := logNonCanonicalCommits(ctx, repoLogger, fetcher, "operator-framework/"+repo, commits[repo], opts); err != nil {
+				return nil, err
+			}
 		} else {
 			repoLogger.Debug("no commits found")
 		}
@@ -459,47 +1104,99 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, stagingDir, cen
 		logger.Debug("no commits found to merge over all repos")
 		return nil, nil
 	}
-	// we would like to intertwine the commits from each upstream repository by date, while
-	// keeping the order of commits from any one repository in the order they were committed in
+	orderedCommits := intertwineCommits(logger, commits, opts.commitOrder == commitOrderTopo)
+
+	if opts.explain {
+		fmt.Println("Commits in intertwined order:")
+		internal.Table(logger.Logger, orderedCommits, "operator-framework/")
+		fmt.Println()
+	}
+
+	// our ordered list is descending, but we need to cherry-pick from the oldest first
+	var reversedCommits []internal.Commit
+	for i := range orderedCommits {
+		reversedCommits = append(reversedCommits, orderedCommits[len(orderedCommits)-i-1])
+	}
+	return reversedCommits, nil
+}
+
+// intertwineCommits orders the commits from each upstream repository by date, while keeping the commits from any one
+// repository in the order they were committed in. With stableTies, ties between repos are broken by name.
+func intertwineCommits(logger *logrus.Entry, commits map[string][]internal.Commit, stableTies bool) []internal.Commit {
 	var orderedCommits []internal.Commit
 	indices := map[string]int{}
+	var repos []string
 	for repo := range commits {
+		if len(commits[repo]) == 0 {
+			continue
+		}
 		indices[repo] = 0
+		repos = append(repos, repo)
+	}
+	if stableTies {
+		// break ties between repos consistently, so the merge across repos is stable
+		sort.Strings(repos)
 	}
-	for {
+	for len(indices) > 0 {
 		// find which repo's commit stack we should pop off to get the next earliest commit
-		nextTime := time.Now()
+		// don't use a sentinel time here, as future-dated commits would never be selected
+		var nextTime time.Time
 		var nextRepo string
+		found := false
 
-		for repo, index := range indices {
-			if commits[repo][index].Date.Before(nextTime) {
+		for _, repo := range repos {
+			index, ok := indices[repo]
+			if !ok {
+				continue
+			}
+			if !found || commits[repo][index].Date.Before(nextTime) {
 				nextTime = commits[repo][index].Date
 				nextRepo = repo
+				found = true
 			}
 		}
 
 		// pop the commit, add it to our list and do housekeeping for our index records
+		logger.WithFields(logrus.Fields{
+			"repo":   nextRepo,
+			"commit": commits[nextRepo][indices[nextRepo]].Hash,
+			"date":   nextTime,
+		}).Debug("selected next-earliest commit")
 		orderedCommits = append(orderedCommits, commits[nextRepo][indices[nextRepo]])
 		if indices[nextRepo] == len(commits[nextRepo])-1 {
 			delete(indices, nextRepo)
 		} else {
 			indices[nextRepo] += 1
 		}
-
-		if len(indices) == 0 {
-			break
-		}
 	}
+	return orderedCommits
+}
 
-	// our ordered list is descending, but we need to cherry-pick from the oldest first
-	var reversedCommits []internal.Commit
-	for i := range orderedCommits {
-		reversedCommits = append(reversedCommits, orderedCommits[len(orderedCommits)-i-1])
+// filterMissingCommits returns the commits that have not been cherry-picked downstream yet. Without --keep-empty,
+// commits whose changes are already present downstream are not missing either.
+func filterMissingCommits(ctx context.Context, logger *logrus.Entry, opts Options, commits []internal.Commit) ([]internal.Commit, error) {
+	var missingCommits []internal.Commit
+	for _, commit := range commits {
+		commitLogger := logger.WithField("commit", commit.Hash)
+		missing, err := isCommitMissing(ctx, commitLogger, commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine if commit is missing: %w", err)
+		}
+		if missing && !opts.keepEmpty && isCommitApplied(ctx, commitLogger, opts.stagingPath(commit.Repo), commit) {
+			// skipped empty commits leave no trace downstream, so without this they would be detected on every run
+			commitLogger.Info("changes from commit are already present downstream, skipping")
+			missing = false
+		}
+		if missing {
+			missingCommits = append(missingCommits, commit)
+		}
 	}
-	return reversedCommits, nil
+	return missingCommits, nil
 }
 
-func isCommitMissing(ctx context.Context, logger *logrus.Entry, stagingDir string, c internal.Commit) (bool, error) {
+// isCommitMissing determines whether c has not been cherry-picked downstream, by the trailers recording it. The log
+// is not limited to the staging directory, as a commit that was kept while empty does not change it.
+func isCommitMissing(ctx context.Context, logger *logrus.Entry, c internal.Commit) (bool, error) {
 	output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
 		"git", "log",
 		"-n", "1",
@@ -507,8 +1204,6 @@ func isCommitMissing(ctx context.Context, logger *logrus.Entry, stagingDir strin
 		"--grep", "Upstream-commit: "+c.Hash,
 		"--all-match",
 		"--pretty=%B",
-		"--",
-		filepath.Join(stagingDir, c.Repo),
 	))
 	if err != nil {
 		return false, err
@@ -516,72 +1211,169 @@ func isCommitMissing(ctx context.Context, logger *logrus.Entry, stagingDir strin
 	return len(output) == 0, nil
 }
 
-func cherryPick(ctx context.Context, logger *logrus.Entry, c internal.Commit, commitArgs []string, delayManifestGeneration bool) error {
-	{
-		output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-			"git", "cherry-pick",
-			"--allow-empty", "--keep-redundant-commits",
-			"-Xsubtree=staging/"+c.Repo, c.Hash,
-		))
+// commitsInRange lists the commits in the explicit range A..B from the upstream repository, instead of detecting them.
+func commitsInRange(ctx context.Context, logger *logrus.Entry, repo, commitRange string, logArgs []string, opts Options, fetcher *internal.Fetcher) ([]internal.Commit, error) {
+	start, end, _ := strings.Cut(commitRange, "..")
+	logger = logger.WithField("range", commitRange)
+	logger.Info("using explicit commit range")
+	fetched, err := fetchUpstream(ctx, logger, fetcher, "operator-framework/"+repo, end, false, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch end of range: %w", err)
+	}
+	output, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+		"git", append(append(logArgs,
+			start+".."+fetched,
+		), opts.PathSpec()...)...,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits in range: %w", err)
+	}
+	var commits []internal.Commit
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		commit, err := internal.Info(ctx, logger, line, ".")
 		if err != nil {
+			return nil, err
+		}
+		commit.Repo = repo
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// isCommitApplied determines whether the changes from c are already present in the staging directory, in which
+// case cherry-picking it would produce an empty commit.
+func isCommitApplied(ctx context.Context, logger *logrus.Entry, stagingPath string, c internal.Commit) bool {
+	patch, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+		"git", "diff", c.Hash+"^", c.Hash,
+	))
+	if err != nil || strings.TrimSpace(patch) == "" {
+		return false
+	}
+	applyCmd := exec.CommandContext(ctx,
+		"git", "apply", "--check", "--reverse", "--cached",
+		"--directory="+stagingPath,
+	)
+	applyCmd.Stdin = strings.NewReader(patch)
+	_, err = internal.RunCommand(logger, applyCmd)
+	return err == nil
+}
+
+// cherryPick cherry-picks c into its staging directory, returning false if it was skipped for being empty. The
+// manifests are only generated when delayManifestGeneration is unset.
+func cherryPick(ctx context.Context, logger *logrus.Entry, c internal.Commit, opts Options, delayManifestGeneration bool) (bool, error) {
+	stagingPath := opts.stagingPath(c.Repo)
+	{
+		cherryPickArgs := []string{"cherry-pick", "--allow-empty"}
+		if opts.keepEmpty {
+			cherryPickArgs = append(cherryPickArgs, "--keep-redundant-commits")
+		}
+		output, err := internal.RunCommand(logger, internal.WithEnv(exec.CommandContext(ctx,
+			"git", append(cherryPickArgs, "-Xsubtree="+stagingPath, c.Hash)...,
+		), opts.GitEnv()...))
+		if err != nil && !opts.keepEmpty && strings.Contains(output, "The previous cherry-pick is now empty") {
+			logger.Info("skipping commit that is now empty")
+			if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+				"git", "cherry-pick", "--skip",
+			)); err != nil {
+				return false, err
+			}
+			return false, nil
+		}
+		if cherryPickErr := err; err != nil {
 			continueCherryPick := false
-			if strings.Contains(output, "vendor/modules.txt deleted in HEAD and modified in") {
+			if deleted := deletedInHeadRegex.FindAllStringSubmatch(output, -1); len(deleted) > 0 {
+				// we remove vendor directories for everything under staging/, but some of the upstream repos have them,
+				// so their changes conflict with the deletion; conflicts on any other deleted file need a human
+				vendorDir := filepath.Join(stagingPath, "vendor")
 				continueCherryPick = true
-				// we remove vendor directories for everything under staging/, but some of the upstream repos have them
-				if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-					"git", "rm", "--cached", "-r", "--ignore-unmatch", "staging/"+c.Repo+"/vendor",
-				)); err != nil {
-					return err
+				for _, match := range deleted {
+					if !strings.HasPrefix(match[1], vendorDir+"/") {
+						logger.WithField("path", match[1]).Warn("file deleted downstream was modified upstream")
+						continueCherryPick = false
+					}
+				}
+				if continueCherryPick {
+					if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+						"git", "rm", "--cached", "-r", "--ignore-unmatch", vendorDir,
+					)); err != nil {
+						return false, err
+					}
 				}
 			}
-			if strings.Contains(output, "Merge conflict in staging/"+c.Repo+"/go.mod") {
+			goMod := filepath.Join(stagingPath, "go.mod")
+			if strings.Contains(output, "Merge conflict in "+goMod) {
 				continueCherryPick = true
 				// Due to the `go mod` commands in the staging directory below, this file may have conflicts,
 				// So resolve it as "theirs" (i.e. incoming), and then use the `go mod` commands to update it
 				// Conflicts can arise due to downstream-only code in a staging directory affecting the
 				// `go mod` command results
 				if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-					"git", "checkout", "--theirs", "--", "staging/"+c.Repo+"/go.mod",
+					"git", "checkout", "--theirs", "--", goMod,
 				)); err != nil {
-					return err
+					return false, err
 				}
 				if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
-					"git", "add", "staging/"+c.Repo+"/go.mod",
+					"git", "add", goMod,
 				)); err != nil {
-					return err
+					return false, err
+				}
+			}
+			rawConflicted, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+				"git", "diff", "--name-only", "--diff-filter=U",
+			))
+			if err != nil {
+				return false, err
+			}
+			owned, err := internal.DownstreamOwned(ctx, logger, "", strings.Fields(rawConflicted))
+			if err != nil {
+				return false, err
+			}
+			for _, path := range owned {
+				continueCherryPick = true
+				logger.WithField("path", path).Info("keeping downstream version of conflicted path listed in " + internal.DownstreamIgnoreFile)
+				for _, args := range [][]string{{"checkout", "--ours", "--", path}, {"add", "--", path}} {
+					if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+						"git", args...,
+					)); err != nil {
+						return false, err
+					}
 				}
 			}
 			if continueCherryPick {
-				if _, err := internal.RunCommand(logger, exec.CommandContext(ctx,
+				if _, err := internal.RunCommand(logger, internal.WithEnv(exec.CommandContext(ctx,
 					"git", "cherry-pick", "--continue",
-				)); err != nil {
-					return err
+				), opts.GitEnv()...)); err != nil {
+					return false, err
 				}
 			} else {
-				return err
+				if opts.ConflictPatchDir != "" {
+					if err := internal.WriteConflictPatch(ctx, logger, "", opts.ConflictPatchDir, c.Repo, c); err != nil {
+						logger.WithError(err).Error("failed to write conflicting patch")
+					}
+				}
+				return false, internal.WithExitCode(internal.ExitConflict, cherryPickErr)
 			}
 		}
 	}
 
-	gomod := []*exec.Cmd{
-		internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "tidy",
-		), os.Environ()...),
-		internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "vendor",
-		), os.Environ()...),
-		internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "verify",
-		), os.Environ()...),
-		internal.WithDir(internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "tidy",
-		), os.Environ()...), filepath.Join("staging", c.Repo)),
-		internal.WithDir(internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "vendor",
-		), os.Environ()...), filepath.Join("staging", c.Repo)),
-		internal.WithDir(internal.WithEnv(exec.CommandContext(ctx,
-			"go", "mod", "verify",
-		), os.Environ()...), filepath.Join("staging", c.Repo)),
+	if err := internal.RestoreDownstreamOwned(ctx, logger, "", opts.GitEnv()); err != nil {
+		return false, fmt.Errorf("failed to restore downstream-owned paths: %w", err)
+	}
+
+	if opts.MaxFileSize > 0 {
+		if err := internal.CheckFileSizes(ctx, logger, "", opts.MaxFileSize, opts.WarnLargeFiles); err != nil {
+			return false, fmt.Errorf("commit %s is too large: %w", c.Hash, err)
+		}
+	}
+
+	for _, dir := range []string{"", stagingPath} {
+		if err := internal.RunGoMod(ctx, logger, opts.Go(), dir, opts.GoEnv(), !opts.NoVendor, opts.GoModRetries); err != nil {
+			return false, err
+		}
 	}
 
 	manifests := []*exec.Cmd{
@@ -590,24 +1382,25 @@ func cherryPick(ctx context.Context, logger *logrus.Entry, c internal.Commit, co
 		), os.Environ()...),
 	}
 
-	commits := []*exec.Cmd{
+	files := append([]string{"go.mod", "go.sum"}, manifestFiles...)
+	var commits []*exec.Cmd
+	if !opts.NoVendor {
+		files = append([]string{"vendor"}, files...)
 		// Necessary for untracked files created via `go mod vendor`
-		exec.CommandContext(ctx,
+		commits = append(commits, exec.CommandContext(ctx,
 			"git", "add", "vendor",
-		),
-		exec.CommandContext(ctx,
-			"git", append([]string{"commit",
-				"--amend", "--allow-empty", "--no-edit",
-				"--trailer", "Upstream-repository: " + c.Repo,
-				"--trailer", "Upstream-commit: " + c.Hash,
-				"staging/" + c.Repo,
-				"vendor", "go.mod", "go.sum",
-				"manifests", "microshift-manifests", "pkg/manifests"},
-				commitArgs...)...,
-		),
-	}
-
-	commands := gomod
+		))
+	}
+	commits = append(commits, internal.WithEnv(exec.CommandContext(ctx,
+		"git", append(append([]string{"-c", "trailer.ifexists=addIfDifferent", "commit",
+			"--amend", "--allow-empty", "--no-edit",
+			"--trailer", "Upstream-repository: " + c.Repo,
+			"--trailer", "Upstream-commit: " + c.Hash,
+			stagingPath},
+			files...), opts.GitCarryCommitArgs()...)...,
+	), opts.GitEnv()...))
+
+	var commands []*exec.Cmd
 	if !delayManifestGeneration {
 		commands = append(commands, manifests...)
 	}
@@ -615,9 +1408,28 @@ func cherryPick(ctx context.Context, logger *logrus.Entry, c internal.Commit, co
 
 	for _, cmd := range commands {
 		if _, err := internal.RunCommand(logger, cmd); err != nil {
-			return err
+			return false, err
 		}
 	}
 
+	return true, nil
+}
+
+// generateManifests generates the manifests and amends them into the last commit.
+func generateManifests(ctx context.Context, logger *logrus.Entry, commitArgs, gitEnv []string) error {
+	for _, cmd := range []*exec.Cmd{
+		internal.WithEnv(exec.CommandContext(ctx,
+			"make", "generate-manifests",
+		), os.Environ()...),
+		internal.WithEnv(exec.CommandContext(ctx,
+			"git", append(append([]string{"commit",
+				"--amend", "--allow-empty", "--no-edit"},
+				manifestFiles...), commitArgs...)...,
+		), gitEnv...),
+	} {
+		if _, err := internal.RunCommand(logger, cmd); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,208 @@
+package v0
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/openshift/operator-framework-tooling/pkg/internal/gittest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIntertwineCommits(t *testing.T) {
+	at := func(hash, repo string, date time.Time) internal.Commit {
+		return internal.Commit{Hash: hash, Repo: repo, Date: date}
+	}
+	past := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name     string
+		commits  map[string][]internal.Commit
+		expected []string
+	}{
+		{
+			name:     "no commits",
+			commits:  map[string][]internal.Commit{},
+			expected: nil,
+		},
+		{
+			name: "one repo keeps its order",
+			commits: map[string][]internal.Commit{
+				"api": {at("a1", "api", past.Add(time.Hour)), at("a2", "api", past)},
+			},
+			expected: []string{"a1", "a2"},
+		},
+		{
+			name: "repos are intertwined by date",
+			commits: map[string][]internal.Commit{
+				"api":      {at("a1", "api", past.Add(3*time.Hour)), at("a2", "api", past.Add(time.Hour))},
+				"registry": {at("r1", "registry", past.Add(2*time.Hour))},
+			},
+			expected: []string{"r1", "a1", "a2"},
+		},
+		{
+			name: "order within a repo is kept even when its dates are not",
+			commits: map[string][]internal.Commit{
+				"api":      {at("a1", "api", past), at("a2", "api", past.Add(3*time.Hour))},
+				"registry": {at("r1", "registry", past.Add(time.Hour))},
+			},
+			expected: []string{"a1", "r1", "a2"},
+		},
+		{
+			name: "ties are broken by repo name",
+			commits: map[string][]internal.Commit{
+				"registry": {at("r1", "registry", past)},
+				"api":      {at("a1", "api", past)},
+			},
+			expected: []string{"a1", "r1"},
+		},
+		{
+			name: "a future-dated commit is still selected",
+			commits: map[string][]internal.Commit{
+				"api":      {at("a1", "api", future)},
+				"registry": {at("r1", "registry", past)},
+			},
+			expected: []string{"r1", "a1"},
+		},
+		{
+			name: "all commits are future-dated",
+			commits: map[string][]internal.Commit{
+				"api":      {at("a1", "api", future.Add(time.Hour)), at("a2", "api", future)},
+				"registry": {at("r1", "registry", future.Add(2*time.Hour))},
+			},
+			expected: []string{"a1", "a2", "r1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, commit := range intertwineCommits(logrus.NewEntry(logrus.New()), tc.commits, true) {
+				actual = append(actual, commit.Hash)
+			}
+			if !slices.Equal(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// writeFiles writes the files, keyed by their path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// keepEmptyFixture sets up a downstream repository as the working directory, as the v0 commands run in it, with the
+// api repository staged under staging/api. It returns an upstream api commit whose change is already present
+// downstream, so that cherry-picking it is empty.
+func keepEmptyFixture(t *testing.T) internal.Commit {
+	t.Helper()
+	gittest.Isolate(t)
+
+	upstream := t.TempDir()
+	gittest.Git(t, upstream, "init", "--initial-branch", "main")
+	writeFiles(t, upstream, map[string]string{"go.mod": "module example.com/api\n\ngo 1.21\n", "api.txt": "v1\n"})
+	gittest.Git(t, upstream, "add", ".")
+	gittest.Git(t, upstream, "commit", "--message", "add api")
+	writeFiles(t, upstream, map[string]string{"api.txt": "v2\n"})
+	gittest.Git(t, upstream, "commit", "--all", "--message", "bump api")
+	hash := gittest.Git(t, upstream, "rev-parse", "HEAD")
+
+	downstream := t.TempDir()
+	gittest.Git(t, downstream, "init", "--initial-branch", "master")
+	writeFiles(t, downstream, map[string]string{
+		"go.mod":                 "module example.com/downstream\n\ngo 1.21\n",
+		"go.sum":                 "",
+		"manifests/m.yaml":       "",
+		"microshift-manifests/m": "",
+		"pkg/manifests/m.yaml":   "",
+		"staging/api/go.mod":     "module example.com/api\n\ngo 1.21\n",
+		"staging/api/api.txt":    "v2\n",
+	})
+	gittest.Git(t, downstream, "add", ".")
+	gittest.Git(t, downstream, "commit", "--message", "downstream already has the change")
+	gittest.Git(t, downstream, "fetch", upstream, "main")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(downstream); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return internal.Commit{Hash: hash, Repo: "api"}
+}
+
+func TestKeepEmpty(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		keepEmpty bool
+	}{
+		{name: "kept", keepEmpty: true},
+		{name: "skipped", keepEmpty: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			commit := keepEmptyFixture(t)
+			ctx := context.Background()
+			logger := logrus.NewEntry(logrus.New())
+			opts := DefaultOptions()
+			opts.keepEmpty = tc.keepEmpty
+			opts.NoVendor = true
+
+			missing, err := filterMissingCommits(ctx, logger, opts, []internal.Commit{commit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// without --keep-empty, the commit would be skipped, so it is not missing as its changes are present
+			if (len(missing) == 1) != tc.keepEmpty {
+				t.Fatalf("expected the commit to be missing: %v, got %v", tc.keepEmpty, missing)
+			}
+
+			before := gittest.Git(t, ".", "rev-parse", "HEAD")
+			picked, err := cherryPick(ctx, logger, commit, opts, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if picked != tc.keepEmpty {
+				t.Fatalf("expected the empty commit to be picked: %v, got %v", tc.keepEmpty, picked)
+			}
+			if tc.keepEmpty {
+				if parent := gittest.Git(t, ".", "rev-parse", "HEAD^"); parent != before {
+					t.Errorf("expected one commit on top of %s, got parent %s", before, parent)
+				}
+				if message := gittest.Git(t, ".", "log", "-1", "--format=%B"); !strings.Contains(message, "Upstream-commit: "+commit.Hash) {
+					t.Errorf("expected the kept commit to record its upstream commit, got %q", message)
+				}
+			} else if after := gittest.Git(t, ".", "rev-parse", "HEAD"); after != before {
+				t.Errorf("expected the skipped commit to leave HEAD at %s, got %s", before, after)
+			}
+
+			// a kept commit is found by its trailers, while a skipped one leaves no trace and is found by its changes
+			missing, err = filterMissingCommits(ctx, logger, opts, []internal.Commit{commit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(missing) != 0 {
+				t.Errorf("expected the commit not to be detected again, got %v", missing)
+			}
+		})
+	}
+}
@@ -4,25 +4,59 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift/operator-framework-tooling/pkg/flags"
 	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/openshift/operator-framework-tooling/pkg/internal/jira"
 	"github.com/sirupsen/logrus"
 	"k8s.io/test-infra/prow/cmd/generic-autobumper/bumper"
 	"k8s.io/test-infra/prow/config/secret"
+	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/github"
 	"k8s.io/test-infra/prow/labels"
 	"sigs.k8s.io/yaml"
 )
 
 const (
-	defaultBranch = "main"
+	defaultBranch      = "main"
+	defaultUpstreamOrg = "operator-framework"
+
+	defaultDropPrefix = "UPSTREAM: <drop>:"
+
+	// synchronizeBranch is the local branch the synchronization is applied on, and pushed from
+	synchronizeBranch = "synchronize"
+	// rewriteGoModBranch is the local branch that --mode=rewrite-gomod rewrites go.mod on, and pushes from
+	rewriteGoModBranch = "rewrite-gomod"
+	// scratchBranch is where the synchronization is applied with --atomic-apply, until it has fully succeeded
+	scratchBranch = "synchronize-scratch"
+
+	// defaultNestedModule is the directory holding the downstream module in each repo
+	defaultNestedModule = "openshift"
+
+	dropReasonOption     = "option-drop"
+	dropReasonMessage    = "message-drop"
+	dropReasonRevert     = "revert-cancel"
+	dropReasonEquivalent = "upstream-equivalent"
+	dropReasonPathFilter = "path-filter"
+
+	cherryPickEmptyKeep = "keep"
+	cherryPickEmptyDrop = "drop"
+
+	// how the downstream branch is merged onto the upstream target, see --base-merge-strategy
+	baseMergeStrategyOurs  = "ours"
+	baseMergeStrategyMerge = "merge"
+	baseMergeStrategyReset = "reset"
 
 	TideMergeMethodMergeLabel = "tide/merge-method-merge"
 	KindSyncLabel             = "kind/sync"
@@ -30,8 +64,13 @@ const (
 
 func DefaultOptions() Options {
 	opts := Options{
-		Options: flags.DefaultOptions(),
+		downstreamBranch: defaultBranch,
+		upstreamOrg:      defaultUpstreamOrg,
+		dropPrefix:       defaultDropPrefix,
+		Options:          flags.DefaultOptions(),
 	}
+	opts.baseMergeStrategy = baseMergeStrategyOurs
+	opts.fetchTags = true
 	opts.Options.PRBaseBranch = defaultBranch
 	return opts
 }
@@ -44,14 +83,73 @@ type Options struct {
 	printPullRequestComment bool
 	forceRemerge            bool
 	ignoreCatalogd          bool
+	upstreamOrg             string
+	downstreamBranch        string
+	squashHousekeeping      bool
+	runCommitChecker        bool
+	bodyMaxCarries          int
+	precheckCarries         bool
+	skipUpToDate            bool
+	editPlan                bool
+	verifyCommand           string
+	handleSubmodules        bool
+	issueTrailer            bool
+	includeMergeCarries     bool
+	continueOnRepoError     bool
+	strictModules           bool
+	strict                  bool
+	versionRangeLabel       bool
+	combineStripCommit      bool
+	atomicApply             bool
+	fetchTags               bool
+	auditUpstreamPRs        bool
+	explainDeps             bool
+
+	cherryPickStrategyOptions flagutil.Strings
+	cherryPickEmpty           string
+	baseMergeStrategy         string
+	allowUnrelatedHistories   bool
+
+	dropPrefix string
+
+	nestedModules flagutil.Strings
+
+	assignOverrides    flagutil.Strings
+	repoAssignOverride map[string][]string
+
+	gitNameOverrides  flagutil.Strings
+	gitEmailOverrides flagutil.Strings
+	repoGitNames      map[string]string
+	repoGitEmails     map[string]string
+
+	repoAliases   flagutil.Strings
+	upstreamNames map[string]string
+
+	targetOverrideFlags flagutil.Strings
+	targetOverrides     map[string]string
+
+	cherryPickCommit string
 
 	dropCommits     string
 	listDropCommits []string
+	droppedOutput   string
+	formatPatchDir  string
+
+	combinedPR         bool
+	combinedPRManifest string
+	// prHosts maps each repo to the repo whose pull request publishes it with --combined-pr
+	prHosts map[string]string
 
 	flags.Options
 }
 
 var dirMap = map[string]string{}
+
+// extraVendor are the directories of the modules besides the root and nested modules that each repo vendors.
+var extraVendor = map[string][]string{
+	"operator-controller": {"testdata/push", "testdata/registry"},
+}
+var nestedModuleMap = map[string]string{}
 var repoList = []string{}
 
 func (o *Options) Bind(fs *flag.FlagSet) {
@@ -60,8 +158,45 @@ func (o *Options) Bind(fs *flag.FlagSet) {
 	fs.BoolVar(&o.pauseOnCherryPickError, "pause-on-cherry-pick-error", o.pauseOnCherryPickError, "When an error occurs during cherry-pick, pause to allow the user to fix.")
 	fs.BoolVar(&o.printPullRequestComment, "print-pull-request-comment", o.printPullRequestComment, "During synchonize mode, print out the pull request comment (for pasting into a PR).")
 	fs.BoolVar(&o.forceRemerge, "force-remerge", o.forceRemerge, "When synchonizing, force a merge of the upstream branch again.")
+	fs.StringVar(&o.upstreamOrg, "upstream-org", o.upstreamOrg, "The upstream GitHub org name.")
+	fs.StringVar(&o.downstreamBranch, "downstream-branch", o.downstreamBranch, "The downstream branch to synchronize onto.")
+	fs.BoolVar(&o.squashHousekeeping, "squash-housekeeping", o.squashHousekeeping, "Squash the generated housekeeping commits into a single commit.")
+	fs.StringVar(&o.verifyCommand, "verify-command", o.verifyCommand, "Command to run in each repository after synchronizing and before pushing, e.g. 'make lint test'. A failure aborts the run.")
+	fs.BoolVar(&o.runCommitChecker, "run-commit-checker", o.runCommitChecker, "After synchronizing, run the commit-checker against the synchronized branch.")
+	fs.IntVar(&o.bodyMaxCarries, "body-max-carries", o.bodyMaxCarries, "Maximum number of carried commits to list in the pull request body. If not specified, lists all of them.")
+	fs.BoolVar(&o.precheckCarries, "precheck-carries", o.precheckCarries, "During summarize mode, test whether each carry applies cleanly on top of the new target.")
+	fs.BoolVar(&o.explainDeps, "explain-deps", o.explainDeps, "Print how operator-controller depends on the other repos: which are replaced in its go.mod with their downstream HEAD, and which must be published before its go.mod can reference them.")
+	fs.BoolVar(&o.auditUpstreamPRs, "audit-upstream-prs", o.auditUpstreamPRs, "Look up the upstream pull requests referenced by carries with UPSTREAM: <PR>: and fail if any was closed without merging, as such carries should be UPSTREAM: <carry>: instead.")
+	fs.BoolVar(&o.skipUpToDate, "skip-uptodate", o.skipUpToDate, "During summarize mode, omit the repos that are up-to-date with their upstream target and only print how many there are.")
+	fs.Var(&o.cherryPickStrategyOptions, "cherry-pick-strategy-option", "Strategy option to pass to git cherry-pick as -X<option> when carrying commits. May be repeated.")
+	fs.StringVar(&o.cherryPickEmpty, "cherry-pick-empty", o.cherryPickEmpty, fmt.Sprintf("How to handle carried commits that become empty. One of %v. If not specified, empty commits fail the cherry-pick.", []string{cherryPickEmptyKeep, cherryPickEmptyDrop}))
+	fs.StringVar(&o.baseMergeStrategy, "base-merge-strategy", o.baseMergeStrategy, fmt.Sprintf("How to create the synchronize branch from the upstream target and the downstream branch. One of %v. A real merge brings in the downstream content, so previous carries will be empty or conflict; a reset drops the downstream history, so the result does not fast-forward the downstream branch.", []string{baseMergeStrategyOurs, baseMergeStrategyMerge, baseMergeStrategyReset}))
+	fs.BoolVar(&o.allowUnrelatedHistories, "allow-unrelated-histories", o.allowUnrelatedHistories, "Pass --allow-unrelated-histories when merging the downstream branch onto the upstream target, e.g. after the upstream history was rewritten.")
+	fs.BoolVar(&o.fetchTags, "fetch-tags", o.fetchTags, "Fetch all upstream tags along with every ref. If false, tags are only fetched when needed: the tag of the go.mod version of a dependent repo, and all tags of the repos that are not up-to-date, to find the crossed tags.")
+	fs.BoolVar(&o.atomicApply, "atomic-apply", o.atomicApply, fmt.Sprintf("Apply the synchronization on a scratch branch, and only replace the %s branch once it has fully succeeded, including go mod, manifests and verification. If not specified, the %s branch is reset and applied in place.", synchronizeBranch, synchronizeBranch))
+	fs.StringVar(&o.dropPrefix, "drop-prefix", o.dropPrefix, "Message prefix for generated commits that are dropped on the next synchronization.")
+	fs.Var(&o.nestedModules, "nested-module", fmt.Sprintf("Directory of the downstream nested module in a repo, as repo=dir. May be repeated. Defaults to %q for every repo.", defaultNestedModule))
+	fs.Var(&o.gitNameOverrides, "git-name-overrides", "The name to use on the git commits of a repo instead of --git-name, as repo=name. Set in the repo's local git config. May be repeated.")
+	fs.Var(&o.gitEmailOverrides, "git-email-overrides", "The email to use on the git commits of a repo instead of --git-email, as repo=email. Set in the repo's local git config. May be repeated.")
+	fs.Var(&o.assignOverrides, "assign-overrides", "Additional assignees for a repo's pull request, as repo=user1,user2. Merged with --assign. May be repeated.")
+	fs.Var(&o.repoAliases, "repo-aliases", "Upstream name of a repo that was renamed or folded into another upstream repository, as old=new. Carries are still detected in the downstream repo under the old name, while the upstream target is fetched and resolved under the new one. May be repeated.")
+	fs.StringVar(&o.cherryPickCommit, "commit", o.cherryPickCommit, fmt.Sprintf("In %s mode, the downstream commit to cherry-pick onto the upstream target of --repo.", flags.CherryPickOne))
+	fs.Var(&o.targetOverrideFlags, "target-override", "Upstream commit to synchronize a repo to instead of the resolved target, as repo=sha. The commit must be reachable from the upstream branch. May be repeated.")
+	fs.BoolVar(&o.handleSubmodules, "handle-submodules", o.handleSubmodules, "After cherry-picking a carry that changes .gitmodules, sync and update the submodules and stage their gitlinks.")
+	fs.BoolVar(&o.issueTrailer, "issue-trailer", o.issueTrailer, "Add an Issue trailer for --issue-ref to the merge commit of the synchronize branch.")
+	fs.BoolVar(&o.includeMergeCarries, "include-merge-carries", o.includeMergeCarries, "Also carry the changes that downstream merge commits made of their own, beyond merging their parents. If not specified, such merges are skipped with a warning.")
+	fs.BoolVar(&o.continueOnRepoError, "continue-on-repo-error", o.continueOnRepoError, "When synchronizing or publishing a repo fails, continue with the other repos and report the failures at the end.")
+	fs.BoolVar(&o.strict, "strict", o.strict, "Fail instead of warning when the upstream target is inconsistent with the expectedMergeBase recorded in the commitchecker.yaml of the downstream branch.")
+	fs.BoolVar(&o.strictModules, "strict-module-consistency", o.strictModules, "Fail instead of warning when the root and nested modules require different versions of the same dependency after vendoring.")
+	fs.BoolVar(&o.versionRangeLabel, "version-range-label", o.versionRangeLabel, "Label the pull request with the upstream versions it moves between, e.g. upstream/v1.2.0-to-v1.3.0.")
+	fs.BoolVar(&o.combineStripCommit, "combine-strip-commit", o.combineStripCommit, "Remove the upstream GitHub configuration in the go mod commit instead of in a commit of its own.")
 	fs.BoolVar(&o.ignoreCatalogd, "ignore-catalogd", o.ignoreCatalogd, "Ignore catalogd repository.")
+	fs.BoolVar(&o.editPlan, "edit-plan", o.editPlan, "Open the carry plan in $EDITOR to reorder or drop commits before cherry-picking.")
 	fs.StringVar(&o.dropCommits, "drop-commits", o.dropCommits, "Comma-separated list of carry commit SHAs to drop.")
+	fs.StringVar(&o.droppedOutput, "dropped-output", o.droppedOutput, "File to write the commits that were dropped instead of carried, and why, as JSON.")
+	fs.BoolVar(&o.combinedPR, "combined-pr", o.combinedPR, "Apply and publish the repos that share a checkout of a downstream monorepo in a single pull request, with a section of the body for each. The repos must synchronize to the same upstream target. If not specified, each repo is published in a pull request of its own.")
+	fs.StringVar(&o.combinedPRManifest, "combined-pr-manifest", o.combinedPRManifest, "With --combined-pr, a JSON file mapping the repo to open each combined pull request for to the other repos it publishes, e.g. {\"operator-controller\": [\"catalogd\"]}, instead of grouping the repos by checkout. The repos of a group must still share a checkout.")
+	fs.StringVar(&o.formatPatchDir, "format-patch-dir", o.formatPatchDir, "Directory to write each repo's carried commits to as a numbered patch series, after detecting them.")
 
 	o.Options.Bind(fs)
 }
@@ -77,86 +212,506 @@ func (o *Options) Validate() error {
 		repoList = append(repoList, "catalogd")
 	}
 
-	for name, val := range dirMap {
+	for _, name := range orderedRepos(dirMap) {
+		val := dirMap[name]
 		if val == "" {
 			return fmt.Errorf("--%s-dir is required", name)
 		}
 	}
 
+	for name := range dirMap {
+		nestedModuleMap[name] = defaultNestedModule
+	}
+	for _, override := range o.nestedModules.Strings() {
+		name, nestedDir, ok := strings.Cut(override, "=")
+		if !ok || nestedDir == "" {
+			return fmt.Errorf("--nested-module must be in the form repo=dir, got %q", override)
+		}
+		repoDir, known := dirMap[name]
+		if !known {
+			return fmt.Errorf("--nested-module: unknown repo %q", name)
+		}
+		if _, err := os.Stat(filepath.Join(repoDir, nestedDir)); err != nil {
+			return fmt.Errorf("--nested-module: %s: %w", override, err)
+		}
+		nestedModuleMap[name] = nestedDir
+	}
+
+	o.repoAssignOverride = map[string][]string{}
+	for _, override := range o.assignOverrides.Strings() {
+		name, assignees, ok := strings.Cut(override, "=")
+		if !ok || assignees == "" {
+			return fmt.Errorf("--assign-overrides must be in the form repo=user1,user2, got %q", override)
+		}
+		if _, known := dirMap[name]; !known {
+			return fmt.Errorf("--assign-overrides: unknown repo %q", name)
+		}
+		o.repoAssignOverride[name] = append(o.repoAssignOverride[name], strings.Split(assignees, ",")...)
+	}
+
+	o.repoGitNames = map[string]string{}
+	for _, override := range o.gitNameOverrides.Strings() {
+		name, gitName, ok := strings.Cut(override, "=")
+		if !ok || gitName == "" {
+			return fmt.Errorf("--git-name-overrides must be in the form repo=name, got %q", override)
+		}
+		if _, known := dirMap[name]; !known {
+			return fmt.Errorf("--git-name-overrides: unknown repo %q", name)
+		}
+		o.repoGitNames[name] = gitName
+	}
+	o.repoGitEmails = map[string]string{}
+	for _, override := range o.gitEmailOverrides.Strings() {
+		name, gitEmail, ok := strings.Cut(override, "=")
+		if !ok || gitEmail == "" {
+			return fmt.Errorf("--git-email-overrides must be in the form repo=email, got %q", override)
+		}
+		if _, known := dirMap[name]; !known {
+			return fmt.Errorf("--git-email-overrides: unknown repo %q", name)
+		}
+		o.repoGitEmails[name] = gitEmail
+	}
+
+	o.targetOverrides = map[string]string{}
+	for _, override := range o.targetOverrideFlags.Strings() {
+		name, sha, ok := strings.Cut(override, "=")
+		if !ok || !shaRegex.MatchString(sha) {
+			return fmt.Errorf("--target-override must be in the form repo=sha, got %q", override)
+		}
+		if _, known := dirMap[name]; !known {
+			return fmt.Errorf("--target-override: unknown repo %q", name)
+		}
+		o.targetOverrides[name] = sha
+	}
+
+	o.upstreamNames = map[string]string{}
+	for _, alias := range o.repoAliases.Strings() {
+		name, upstream, ok := strings.Cut(alias, "=")
+		if !ok || upstream == "" {
+			return fmt.Errorf("--repo-aliases must be in the form old=new, got %q", alias)
+		}
+		if !slices.Contains(repoList, name) {
+			return fmt.Errorf("--repo-aliases: unknown repo %q", name)
+		}
+		o.upstreamNames[name] = upstream
+	}
+
+	if flags.Mode(o.Mode) == flags.CherryPickOne {
+		if _, known := dirMap[o.GithubRepo]; !known {
+			return fmt.Errorf("--mode=%s requires --repo to be one of %v, got %q", flags.CherryPickOne, orderedRepos(dirMap), o.GithubRepo)
+		}
+		if o.cherryPickCommit == "" {
+			return fmt.Errorf("--mode=%s requires --commit", flags.CherryPickOne)
+		}
+	}
+
+	if o.dropPrefix == "" {
+		return fmt.Errorf("--drop-prefix must not be empty")
+	}
+
+	switch o.cherryPickEmpty {
+	case "", cherryPickEmptyKeep, cherryPickEmptyDrop:
+	default:
+		return fmt.Errorf("--cherry-pick-empty must be one of %v", []string{cherryPickEmptyKeep, cherryPickEmptyDrop})
+	}
+
+	switch o.baseMergeStrategy {
+	case baseMergeStrategyOurs, baseMergeStrategyMerge, baseMergeStrategyReset:
+	default:
+		return fmt.Errorf("--base-merge-strategy must be one of %v", []string{baseMergeStrategyOurs, baseMergeStrategyMerge, baseMergeStrategyReset})
+	}
+
+	if o.issueTrailer {
+		if o.IssueRef == flags.DefaultIssueRef {
+			return fmt.Errorf("--issue-trailer requires --issue-ref")
+		}
+		if o.baseMergeStrategy == baseMergeStrategyReset {
+			return fmt.Errorf("--issue-trailer requires a --base-merge-strategy that creates a merge commit")
+		}
+	}
+
 	if o.dropCommits != "" {
 		o.listDropCommits = strings.Split(o.dropCommits, ",")
 	}
 
+	if o.combinedPRManifest != "" && !o.combinedPR {
+		return fmt.Errorf("--combined-pr-manifest requires --combined-pr")
+	}
+	if o.combinedPR {
+		if err := o.groupPullRequests(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (o *Options) mergeTrailer() string {
+	if !o.issueTrailer {
+		return ""
+	}
+	return "Issue: " + o.IssueRef
+}
+
+// setRepoCommitter overrides the committer identity of repo in its local git config.
+func (o *Options) setRepoCommitter(ctx context.Context, logger *logrus.Entry, repo string) error {
+	name, nameOverridden := o.repoGitNames[repo]
+	email, emailOverridden := o.repoGitEmails[repo]
+	if !nameOverridden && !emailOverridden {
+		return nil
+	}
+	if !nameOverridden {
+		name = o.GitName
+	}
+	if !emailOverridden {
+		email = o.GitEmail
+	}
+	return internal.SetLocalCommitter(ctx, logger, dirMap[repo], name, email)
+}
+
+func (o *Options) assignees(ctx context.Context, logger *logrus.Entry, repo string, config Config) []string {
+	assign := strings.Split(o.Assign, ",")
+	others := o.repoAssignOverride[repo]
+	if o.CcOwners {
+		others = append(slices.Clone(others), internal.CommitOwners(ctx, logger, dirMap[repo], "", config.Additional)...)
+	}
+	for _, who := range others {
+		if !slices.Contains(assign, who) {
+			assign = append(assign, who)
+		}
+	}
+	return assign
+}
+
+func (o *Options) cherryPickArgs() []string {
+	var args []string
+	for _, option := range o.cherryPickStrategyOptions.Strings() {
+		args = append(args, "-X"+option)
+	}
+	if o.cherryPickEmpty == cherryPickEmptyKeep {
+		args = append(args, "--allow-empty", "--keep-redundant-commits")
+	}
+	return args
+}
+
 // Config describes how to update a repo to the intended state.
 type Config struct {
 	Target     internal.Commit   `json:"target"`
 	Additional []internal.Commit `json:"additional"`
+	// Tags are the upstream tags crossed when moving to the target.
+	Tags []string `json:"tags,omitempty"`
+	// Dropped are the downstream commits that are not carried forward.
+	Dropped []internal.DroppedCommit `json:"dropped,omitempty"`
 }
 
 func Run(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	// commits are looked up repeatedly during a run, but the cache must not outlive it
+	ctx = internal.WithInfoCache(ctx)
+	if flags.Mode(opts.Mode) == flags.ValidateConfig {
+		return internal.WithExitCode(internal.ExitConfig, validateConfig(ctx, logger, opts))
+	}
+	if flags.Mode(opts.Mode) == flags.Doctor {
+		return doctor(ctx, logger, opts)
+	}
+
+	if opts.Mutates() {
+		for _, repo := range orderedRepos(dirMap) {
+			// the repos published in one pull request with --combined-pr share a checkout, which is locked once
+			if opts.prHost(repo) != repo {
+				continue
+			}
+			unlock, err := internal.LockRepo(ctx, logger.WithField("repo", repo), dirMap[repo])
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+	}
+
+	if opts.DumpScript != "" {
+		stopRecording, err := internal.RecordScript(opts.DumpScript)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := stopRecording(); err != nil {
+				logger.WithError(err).Error("failed to write script")
+			}
+		}()
+	}
+
+	if opts.OTLPEndpoint != "" {
+		stopTracing := internal.StartTracing(opts.OTLPEndpoint, "operator-framework-tooling-v1", map[string]string{"mode": opts.Mode})
+		defer func() {
+			if err := stopTracing(); err != nil {
+				logger.WithError(err).Warn("failed to export traces")
+			}
+		}()
+	}
+
+	if opts.GoBin != "" {
+		if err := internal.LogGoVersion(ctx, logger.WithField("phase", "setup"), opts.Go(), opts.GoEnv()); err != nil {
+			return err
+		}
+	}
+
+	bodyTemplate, err := opts.ParseBodyTemplate()
+	if err != nil {
+		return internal.WithExitCode(internal.ExitConfig, fmt.Errorf("failed to parse body template: %w", err))
+	}
+
+	for _, repo := range orderedRepos(dirMap) {
+		dir := dirMap[repo]
+		checkDownstreamBranch(ctx, logger.WithField("repo", repo), dir, opts.downstreamBranch)
+	}
+
 	commits := map[string]Config{}
-	var err error
+	// without --tags, git still follows the tags pointing into the fetched history unless told not to
+	fetchArgs := []string{"--no-tags"}
+	if opts.fetchTags {
+		fetchArgs = []string{"--tags"}
+	}
+	fetcher := internal.NewFetcher(opts.BatchFetch, opts.GitFetchArgs(), fetchArgs...)
+	if flags.Mode(opts.Mode) == flags.RewriteGoMod {
+		return rewriteGoModOnly(ctx, logger, opts, fetcher)
+	}
+	var targets map[string]string
 	if opts.CommitFileInput != "" {
-		rawCommits, err := os.ReadFile(opts.CommitFileInput)
+		metadata, err := internal.ReadPlan(opts.CommitFileInput, &commits)
 		if err != nil {
-			return fmt.Errorf("could not read input file: %w", err)
+			return err
 		}
-		if err := json.Unmarshal(rawCommits, &commits); err != nil {
-			return fmt.Errorf("could not unmarshal input commits: %w", err)
+		if metadata != nil {
+			if _, err := fetcher.Fetch(ctx, logger.WithField("phase", "check plan"), dirMap["operator-controller"], upstreamRemote("operator-controller", opts), "HEAD"); err != nil {
+				return fmt.Errorf("failed to fetch upstream: %w", err)
+			}
+			targets, err = upstreamTargets(ctx, logger.WithField("phase", "check plan"), opts, fetcher)
+			if err != nil {
+				return err
+			}
+		}
+		if err := internal.CheckPlan(logger.WithField("phase", "check plan"), metadata, targets, opts.StrictPlan); err != nil {
+			return err
 		}
 	} else {
-		commits, err = detectNewCommits(ctx, logger.WithField("phase", "detect"), dirMap, opts)
+		span := internal.StartPhase(logger.WithField("phase", "detect"), "detect", nil)
+		commits, err = detectNewCommits(ctx, logger.WithField("phase", "detect"), dirMap, opts, fetcher)
+		span.End(err)
+		if err != nil {
+			return fmt.Errorf("failed to detect commits: %w", err)
+		}
+		targets, err = upstreamTargets(ctx, logger.WithField("phase", "detect"), opts, fetcher)
 		if err != nil {
-			logger.WithError(err).Fatal("failed to detect commits")
+			return err
+		}
+	}
+
+	if opts.GithubOutputFile != "" {
+		if err := writeGitHubOutputs(opts.GithubOutputFile, commits, targets); err != nil {
+			return err
+		}
+	}
+
+	if flags.Mode(opts.Mode) == flags.Lag {
+		return printLag(ctx, logger, opts, commits)
+	}
+
+	if opts.editPlan {
+		if err := editPlan(ctx, logger, commits); err != nil {
+			return err
+		}
+	}
+
+	if opts.auditUpstreamPRs {
+		gc, err := opts.ReadGitHubClient()
+		if err != nil {
+			logger.WithError(err).Warn("failed to create a GitHub client, not auditing upstream pull requests")
+		} else {
+			for _, repo := range orderedRepos(commits) {
+				if err := internal.AuditUpstreamPRs(logger.WithField("repo", repo), gc, opts.upstreamOrg, commits[repo].Additional); err != nil {
+					return fmt.Errorf("%s: %w", repo, err)
+				}
+			}
+		}
+	}
+
+	if opts.RequireSignedUpstream {
+		verifier := opts.SignatureVerifier()
+		for _, repo := range orderedRepos(commits) {
+			config := commits[repo]
+			repoLogger := logger.WithField("repo", repo)
+			if err := verifier.Verify(ctx, repoLogger, dirMap[repo], config.Target.Hash); err != nil {
+				return fmt.Errorf("%s: refusing to synchronize to an unverified upstream target: %w", repo, err)
+			}
+			if !opts.RequireSignedCarries {
+				continue
+			}
+			for _, commit := range config.Additional {
+				if err := verifier.Verify(ctx, repoLogger, dirMap[repo], commit.Hash); err != nil {
+					return fmt.Errorf("%s: refusing to carry an unverified commit: %w", repo, err)
+				}
+			}
+		}
+	}
+
+	if opts.explainDeps {
+		if err := explainDeps(ctx, logger, opts, commits, fetcher); err != nil {
+			return err
 		}
 	}
 
 	if opts.CommitFileOutput != "" {
-		commitsJson, err := json.Marshal(commits)
+		if err := internal.WritePlan(opts.CommitFileOutput, internal.NewPlanMetadata(targets), commits); err != nil {
+			return err
+		}
+	}
+
+	if opts.droppedOutput != "" {
+		dropped := map[string][]internal.DroppedCommit{}
+		for _, repo := range orderedRepos(commits) {
+			config := commits[repo]
+			dropped[repo] = config.Dropped
+		}
+		droppedJson, err := json.Marshal(dropped)
 		if err != nil {
-			return fmt.Errorf("could not marshal commits: %w", err)
+			return fmt.Errorf("could not marshal dropped commits: %w", err)
+		}
+		if err := os.WriteFile(opts.droppedOutput, droppedJson, 0666); err != nil {
+			return fmt.Errorf("could not write dropped commits: %w", err)
 		}
-		if err := os.WriteFile(opts.CommitFileOutput, commitsJson, 0666); err != nil {
-			return fmt.Errorf("could not write commits: %w", err)
+	}
+
+	if opts.formatPatchDir != "" {
+		for _, repo := range orderedRepos(commits) {
+			config := commits[repo]
+			if err := formatPatches(ctx, logger.WithField("repo", repo), dirMap[repo], filepath.Join(opts.formatPatchDir, repo), config.Additional); err != nil {
+				return fmt.Errorf("failed to write patches: %w", err)
+			}
 		}
 	}
 
+	if opts.DetectOnly {
+		logger.WithField("repos", len(commits)).Info("detected commits, exiting")
+		return nil
+	}
+
+	if len(commits) == 0 {
+		logger.Info("Current repository state is up-to-date with upstream, nothing to push.")
+		return nil
+	}
+
 	// Get the tools the repo needs via bingo
 	if err := internal.RunBingo(ctx, logger.WithField("phase", "bingo")); err != nil {
-		logger.WithError(err).Fatal("failed to setup tools via bingo")
+		return fmt.Errorf("failed to setup tools via bingo: %w", err)
+	}
+
+	if flags.Mode(opts.Mode) == flags.CherryPickOne {
+		config, ok := commits[opts.GithubRepo]
+		if !ok {
+			return fmt.Errorf("%s is up-to-date with upstream, there is no target to cherry-pick onto", opts.GithubRepo)
+		}
+		return cherryPickOne(ctx, logger.WithField("repo", opts.GithubRepo), opts, opts.GithubRepo, config)
+	}
+
+	// repoErrors records the repos that failed with --continue-on-repo-error, which are skipped from then on
+	repoErrors := map[string]error{}
+	handleRepoError := func(repo string, err error) error {
+		if !opts.continueOnRepoError {
+			return err
+		}
+		logger.WithField("repo", repo).WithError(err).Error("repo failed, continuing with the other repos")
+		repoErrors[repo] = err
+		return nil
 	}
 
-	cherryPickAll := func() {
+	// pullRequests groups the repos that are applied and published together, keyed by the repo whose checkout they share
+	pullRequests := opts.pullRequests(commits)
+
+	cherryPickAll := func() error {
 		if err := internal.SetCommitter(ctx, logger.WithField("phase", "setup"), opts.GitName, opts.GitEmail); err != nil {
-			logger.WithError(err).Fatal("failed to set committer")
+			return fmt.Errorf("failed to set committer: %w", err)
+		}
+		if opts.ResetDates {
+			date := time.Now()
+			logger.WithField("phase", "setup").WithField("date", date.Format(time.RFC3339)).Info("setting the committer date of new commits")
+			opts.PinCommitterDate(date)
+		}
+		for _, repo := range orderedRepos(dirMap) {
+			dir := dirMap[repo]
+			if err := internal.ApplyGitConfig(ctx, logger.WithField("repo", repo), dir, opts.GitConfig.Strings()); err != nil {
+				return err
+			}
+			// a repo sharing the checkout of another is committed to under the identity of the repo publishing it
+			if opts.prHost(repo) != repo {
+				continue
+			}
+			if err := opts.setRepoCommitter(ctx, logger.WithField("repo", repo), repo); err != nil {
+				return fmt.Errorf("failed to set committer: %w", err)
+			}
 		}
-		for repo, config := range commits {
+		applyBranch := synchronizeBranch
+		if opts.atomicApply {
+			applyBranch = scratchBranch
+		}
+		for _, repo := range orderedRepos(pullRequests) {
 			commitLogger := logger.WithField("repo", repo)
-			if err := applyConfig(ctx, commitLogger, "operator-framework", repo, "main", dirMap[repo], config, opts.GitCommitArgs(), opts.pauseOnCherryPickError, opts.Options.DelayManifestGeneration); err != nil {
-				logger.WithError(err).Fatal("failed to merge to upstream")
-			}
-		}
-		// we need the operator-framework-operator-controller go.mod to point to the downstream libraries
-		// that we're synchronizing above, but we can't have replace directives in the go.mod until the
-		// downstream repositories have the desired git state already published. Therefore, only if we
-		// found that the repos are up-to-date (they are not in the commits map) can we do the replacing.
-		otherCommits := map[string]string{}
-		for _, repo := range repoList {
-			if _, ok := commits[repo]; !ok {
-				commit, err := determineDownstreamHead(ctx, logger.WithField("repo", repo), dirMap[repo], repo, opts)
+			if err := func() error {
+				config, err := combineConfigs(pullRequests[repo], commits)
 				if err != nil {
-					logger.WithError(err).Fatal("failed to determine other repo HEAD")
+					return err
+				}
+				if err := applyConfig(ctx, commitLogger, repo, "main", applyBranch, dirMap[repo], config, opts); err != nil {
+					return fmt.Errorf("failed to merge to upstream: %w", err)
+				}
+				if opts.runCommitChecker {
+					if err := runCommitChecker(ctx, commitLogger, dirMap[repo], opts.downstreamBranch); err != nil {
+						return fmt.Errorf("failed to verify commits: %w", err)
+					}
+				}
+				if opts.verifyCommand != "" {
+					if err := runVerifyCommand(ctx, commitLogger, dirMap[repo], opts.verifyCommand, opts.pauseOnCherryPickError); err != nil {
+						return fmt.Errorf("failed to verify synchronized branch: %w", err)
+					}
+				}
+				return nil
+			}(); err != nil {
+				if err := handleRepoError(repo, err); err != nil {
+					return err
+				}
+			}
+		}
+		otherCommits, err := downstreamReplaces(ctx, logger, opts, commits, fetcher)
+		if err != nil {
+			return err
+		}
+		if _, failed := repoErrors[opts.prHost("operator-controller")]; !failed {
+			if err := rewriteGoMod(ctx, logger.WithField("repo", "operator-controller"), opts.upstreamOrg, dirMap["operator-controller"], otherCommits, opts.GitGeneratedCommitArgs(), opts.GitEnv(), opts.GoEnv(), opts.Go(), opts.NoVendor, opts.GoModRetries, opts.dropPrefix); err != nil {
+				return fmt.Errorf("failed to rewrite go mod: %w", err)
+			}
+		}
+		if !opts.NoVendor {
+			for _, repo := range orderedRepos(pullRequests) {
+				if _, failed := repoErrors[repo]; failed {
+					continue
+				}
+				if err := verifyVendor(ctx, logger.WithField("repo", repo), repo, opts); err != nil {
+					if err := handleRepoError(repo, err); err != nil {
+						return err
+					}
 				}
-				otherCommits[repo] = commit
 			}
 		}
-		delete(otherCommits, "operator-controller")
-		if err := rewriteGoMod(ctx, logger.WithField("repo", "operator-controller"), dirMap["operator-controller"], otherCommits, opts.GitCommitArgs()); err != nil {
-			logger.WithError(err).Fatal("failed to rewrite go mod")
+		if opts.atomicApply {
+			for _, repo := range orderedRepos(pullRequests) {
+				if _, failed := repoErrors[repo]; failed {
+					continue
+				}
+				if err := promoteScratchBranch(ctx, logger.WithField("repo", repo), dirMap[repo]); err != nil {
+					return fmt.Errorf("failed to replace %s branch: %w", synchronizeBranch, err)
+				}
+			}
 		}
+		return nil
 	}
 
 	labelsToAdd := []string{
@@ -169,99 +724,347 @@ func Run(ctx context.Context, logger *logrus.Logger, opts Options) error {
 
 	switch flags.Mode(opts.Mode) {
 	case flags.Summarize:
-		for repo, info := range commits {
+		var upToDate int
+		for _, repo := range append([]string{"operator-controller"}, repoList...) {
+			info, ok := commits[repo]
+			if !ok {
+				if opts.skipUpToDate {
+					upToDate++
+				} else {
+					fmt.Printf("openshift/operator-framework-%s: up-to-date\n\n", repo)
+				}
+				continue
+			}
+			if opts.precheckCarries {
+				if err := precheckCarries(ctx, logger.WithField("repo", repo), dirMap[repo], &info); err != nil {
+					return fmt.Errorf("failed to precheck carries: %w", err)
+				}
+			}
 			fmt.Printf("openshift/operator-framework-%s: updating to:\n", repo)
-			internal.Table(logger, []internal.Commit{info.Target}, "operator-framework/")
+			internal.Table(logger, []internal.Commit{info.Target}, opts.upstreamOrg+"/")
+			if len(info.Tags) > 0 {
+				fmt.Printf(" crossing upstream tags: %s\n", strings.Join(info.Tags, ", "))
+			}
 			fmt.Println(" + additional commits to cherry-pick on top:")
 			internal.Table(logger, info.Additional, "openshift/operator-framework-")
 			fmt.Println()
 		}
+		if upToDate > 0 {
+			fmt.Printf("%d repos up-to-date\n", upToDate)
+		}
 	case flags.Synchronize:
-		cherryPickAll()
+		if err := cherryPickAll(); err != nil {
+			return err
+		}
 		if opts.printPullRequestComment {
-			for repo, config := range commits {
+			for _, repo := range orderedRepos(pullRequests) {
+				if _, failed := repoErrors[repo]; failed {
+					continue
+				}
+				config, err := combineConfigs(pullRequests[repo], commits)
+				if err != nil {
+					return err
+				}
 				s := fmt.Sprintf("For repo openshift/operator-framework-%s", repo)
 				fmt.Println(strings.Repeat("=", len(s)))
 				fmt.Println(s)
 				fmt.Println(strings.Repeat("=", len(s)))
-				s = internal.GetBodyV1(config.Target, config.Additional, strings.Split(opts.Assign, ","))
+				s, err = opts.pullRequestBody(ctx, logger.WithField("repo", repo), bodyTemplate, pullRequests[repo], commits, "")
+				if err != nil {
+					return err
+				}
 				fmt.Println(s)
-				for _, label := range labelsToAdd {
+				for _, label := range opts.repoLabels(ctx, logger.WithField("repo", repo), repo, config, labelsToAdd) {
 					fmt.Printf("/label %s\n", label)
 				}
 			}
 		}
 	case flags.Publish:
-		client, err := opts.GitHubClient(opts.DryRun)
-		if err != nil {
-			return fmt.Errorf("failed to create a GitHub client: %w", err)
+		if err := cherryPickAll(); err != nil {
+			return err
 		}
-
-		cherryPickAll()
 		gc, err := opts.GitHubOptions.GitHubClient(opts.DryRun)
 		if err != nil {
 			return fmt.Errorf("error getting GitHub client: %w", err)
 		}
 		gc.SetMax404Retries(0)
 
-		stdout := bumper.HideSecretsWriter{Delegate: os.Stdout, Censor: secret.Censor}
-		stderr := bumper.HideSecretsWriter{Delegate: os.Stderr, Censor: secret.Censor}
-
 		remoteBranch := "synchronize-upstream"
-		title := "NO-ISSUE: Synchronize From Upstream Repositories"
-		for repo, config := range commits {
-			fork, err := client.EnsureFork(opts.GithubLogin, "openshift", "operator-framework-"+repo)
-			if err != nil {
-				return fmt.Errorf("could not ensure fork: %w", err)
-			}
-
-			if err := bumper.MinimalGitPush(
-				fmt.Sprintf(
-					"https://%s:%s@github.com/%s/%s.git",
-					opts.GithubLogin, string(secret.GetTokenGenerator(opts.GitHubOptions.TokenPath)()), opts.GithubLogin, fork,
-				),
-				remoteBranch, stdout, stderr, opts.DryRun, bumper.WithContext(ctx), bumper.WithDir(dirMap[repo])); err != nil {
-				return fmt.Errorf("Failed to push changes.: %w", err)
-			}
-
+		// the published pull requests are reported to Jira together once all repos are done
+		var syncs []jira.Sync
+		publish := func(repo string, config Config, members []string) error {
 			if opts.SelfApprove {
 				logger.Infof("Self-approving PR by adding the %q and %q labels", labels.Approved, labels.LGTM)
 				labelsToAdd = append(labelsToAdd, labels.Approved, labels.LGTM)
 			}
-			if err := bumper.UpdatePullRequestWithLabels(gc, opts.GithubOrg, fork, title,
-				internal.GetBodyV1(config.Target, config.Additional, strings.Split(opts.Assign, ",")),
-				opts.GithubLogin+":"+remoteBranch, opts.PRBaseBranch, remoteBranch, true, labelsToAdd, opts.DryRun); err != nil {
-				return fmt.Errorf("PR creation failed.: %w", err)
+			repoLogger := logger.WithField("repo", repo)
+			link, err := opts.publishPullRequest(ctx, repoLogger, gc, repo, remoteBranch, opts.PRTitle(), opts.repoLabels(ctx, repoLogger, repo, config, labelsToAdd), func(compareHead string) (string, error) {
+				return opts.pullRequestBody(ctx, repoLogger, bodyTemplate, members, commits, compareHead)
+			})
+			if err != nil || opts.ForkOnly {
+				return err
+			}
+			for _, member := range members {
+				syncs = append(syncs, jira.Sync{Repo: member, PullRequest: link, Target: commits[member].Target.Hash, Carries: len(commits[member].Additional)})
+			}
+			return nil
+		}
+		for _, repo := range orderedRepos(pullRequests) {
+			if _, failed := repoErrors[repo]; failed {
+				continue
+			}
+			config, err := combineConfigs(pullRequests[repo], commits)
+			if err != nil {
+				return err
+			}
+			span := internal.StartPhase(logger.WithField("repo", repo), "push", nil)
+			err = publish(repo, config, pullRequests[repo])
+			span.End(err)
+			if err != nil {
+				if err := handleRepoError(repo, err); err != nil {
+					return err
+				}
 			}
 		}
+		if jc, err := opts.JiraClient(); err != nil {
+			logger.WithError(err).Warn("failed to create Jira client, not reporting to Jira")
+		} else if jc != nil {
+			jc.Report(ctx, logger.WithField("phase", "jira"), syncs, opts.JiraTransition, opts.DryRun)
+		}
 	}
-	return nil
+	return repoErrorSummary(repoErrors)
 }
 
-func determineDownstreamHead(ctx context.Context, logger *logrus.Entry, dir, repo string, opts Options) (string, error) {
-	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "fetch", "--tags", downstreamRemote(repo, opts),
-	), dir)); err != nil {
-		return "", fmt.Errorf("failed to fetch upstream: %w", err)
+func repoErrorSummary(repoErrors map[string]error) error {
+	if len(repoErrors) == 0 {
+		return nil
 	}
-	commitSha, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "rev-parse", "FETCH_HEAD",
-	), dir))
+	var repos []string
+	for repo := range repoErrors {
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+	var errs []error
+	for _, repo := range repos {
+		errs = append(errs, fmt.Errorf("%s: %w", repo, repoErrors[repo]))
+	}
+	return fmt.Errorf("failed to synchronize repos %s: %w", strings.Join(repos, ", "), errors.Join(errs...))
+}
+
+// validateConfig checks that each repository is configured correctly, without modifying anything.
+func validateConfig(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	var checks []internal.Check
+	modules, err := internal.LoadModuleCache("")
 	if err != nil {
-		return "", fmt.Errorf("failed to parse upstream HEAD: %w", err)
+		return err
 	}
-	return strings.TrimSpace(commitSha), nil
+	for _, repo := range append([]string{"operator-controller"}, repoList...) {
+		repoLogger := logger.WithField("repo", repo)
+		dir := dirMap[repo]
+		checks = append(checks, internal.Check{Repo: repo, Name: "git repository " + dir, Err: internal.CheckGitRepo(ctx, repoLogger, dir)})
+		if upstream := opts.upstreamName(repo); upstream != "operator-controller" {
+			module := fmt.Sprintf("github.com/%s/%s", opts.upstreamOrg, upstream)
+			_, err := modules.Version(ctx, repoLogger, opts.Go(), dirMap["operator-controller"], "", module, opts.GoEnv())
+			checks = append(checks, internal.Check{Repo: repo, Name: "module " + module, Err: err})
+		}
+		if !opts.Offline {
+			for _, remote := range []string{upstreamRemote(repo, opts), downstreamRemote(repo, opts)} {
+				checks = append(checks, internal.Check{Repo: repo, Name: "remote " + remote, Err: internal.CheckRemote(ctx, repoLogger, remote)})
+			}
+		}
+	}
+	return internal.ReportChecks(logger, checks)
 }
 
-var syntheticVersionRegex = regexp.MustCompile(`[^-]+-(?:[0-9]+\.)[0-9]{14}-([0-9a-f]+)`)
+// doctor checks that the remotes are reachable and that the GitHub token is valid.
+func doctor(ctx context.Context, logger *logrus.Logger, opts Options) error {
+	var checks []internal.Check
+	for _, repo := range append([]string{"operator-controller"}, repoList...) {
+		repoLogger := logger.WithField("repo", repo)
+		for _, remote := range []string{upstreamRemote(repo, opts), downstreamRemote(repo, opts)} {
+			checks = append(checks, internal.Check{Repo: repo, Name: "remote " + remote, Err: internal.CheckRemote(ctx, repoLogger, remote)})
+		}
+	}
+	checks = append(checks, internal.Check{Repo: opts.GithubLogin, Name: "GitHub token", Err: internal.CheckGitHubToken(ctx, opts.GitHubOptions.TokenPath, opts.GithubLogin)})
+	return internal.ReportChecks(logger, checks)
+}
 
-func upstreamRemote(repo string, opts Options) string {
+// writeGitHubOutputs writes the targets and numbers of carries as step outputs.
+func writeGitHubOutputs(path string, commits map[string]Config, targets map[string]string) error {
+	outputs := map[string]string{}
+	var carries int
+	for _, repo := range append([]string{"operator-controller"}, repoList...) {
+		target := targets[repo]
+		config, changed := commits[repo]
+		if changed {
+			target = config.Target.Hash
+			carries += len(config.Additional)
+		}
+		outputs[repo+"_target_sha"] = target
+		outputs[repo+"_carry_count"] = strconv.Itoa(len(config.Additional))
+		outputs[repo+"_changed"] = strconv.FormatBool(changed)
+	}
+	outputs["target_sha"] = outputs["operator-controller_target_sha"]
+	outputs["carry_count"] = strconv.Itoa(carries)
+	outputs["changed"] = strconv.FormatBool(len(commits) > 0)
+	return internal.WriteGitHubOutputs(path, outputs)
+}
+
+// printLag prints how far each downstream branch is behind its upstream target.
+func printLag(ctx context.Context, logger *logrus.Logger, opts Options, commits map[string]Config) error {
+	var lags []internal.Lag
+	for _, repo := range append([]string{"operator-controller"}, repoList...) {
+		config, ok := commits[repo]
+		if !ok {
+			lags = append(lags, internal.Lag{Repo: repo})
+			continue
+		}
+		repoLogger := logger.WithField("repo", repo)
+		mergeBase, err := internal.RunCommand(repoLogger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "merge-base", opts.downstreamBranch, config.Target.Hash,
+		), dirMap[repo]))
+		if err != nil {
+			return fmt.Errorf("failed to determine last synchronized commit for %s: %w", repo, err)
+		}
+		synchronized, err := internal.Info(ctx, repoLogger, strings.TrimSpace(mergeBase), dirMap[repo])
+		if err != nil {
+			return fmt.Errorf("failed to determine commit info: %w", err)
+		}
+		rawCount, err := internal.RunCommand(repoLogger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "rev-list", "--count", synchronized.Hash+".."+config.Target.Hash,
+		), dirMap[repo]))
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(rawCount))
+		if err != nil {
+			return fmt.Errorf("invalid commit count %q: %w", rawCount, err)
+		}
+		lags = append(lags, internal.Lag{Repo: repo, Commits: count, Behind: config.Target.Date.Sub(synchronized.Date)})
+	}
+	return internal.PrintLag(os.Stdout, lags, opts.LagJSON)
+}
+
+// upstreamTargets determines the targets from the last fetched upstream operator-controller commit.
+func upstreamTargets(ctx context.Context, logger *logrus.Entry, opts Options, fetcher *internal.Fetcher) (map[string]string, error) {
+	commit, err := fetcher.Resolve(ctx, logger, dirMap["operator-controller"], upstreamRemote("operator-controller", opts), "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"operator-controller": commit}, nil
+}
+
+// downstreamReplaces determines the downstream commits to replace the upstream modules with, by repo.
+func downstreamReplaces(ctx context.Context, logger *logrus.Logger, opts Options, commits map[string]Config, fetcher *internal.Fetcher) (map[string]string, error) {
+	// we need the operator-framework-operator-controller go.mod to point to the downstream libraries
+	// that we're synchronizing, but we can't have replace directives in the go.mod until the
+	// downstream repositories have the desired git state already published. Therefore, only if we
+	// found that the repos are up-to-date (they are not in the commits map) can we do the replacing.
+	otherCommits := map[string]string{}
+	for _, repo := range repoList {
+		if _, ok := commits[repo]; !ok {
+			commit, err := determineDownstreamHead(ctx, logger.WithField("repo", repo), dirMap[repo], repo, opts, fetcher)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine other repo HEAD: %w", err)
+			}
+			otherCommits[repo] = commit
+		}
+	}
+	delete(otherCommits, "operator-controller")
+	return otherCommits, nil
+}
+
+func explainDeps(ctx context.Context, logger *logrus.Logger, opts Options, commits map[string]Config, fetcher *internal.Fetcher) error {
+	replaces, err := downstreamReplaces(ctx, logger, opts, commits, fetcher)
+	if err != nil {
+		return err
+	}
+	status := func(repo string) string {
+		if _, ok := commits[repo]; ok {
+			return "synchronizing"
+		}
+		return "up-to-date"
+	}
+	fmt.Printf("operator-controller (%s)\n", status("operator-controller"))
+	var pending []string
+	for i, repo := range repoList {
+		branch := "├──"
+		if i == len(repoList)-1 {
+			branch = "└──"
+		}
+		if commit, ok := replaces[repo]; ok {
+			fmt.Printf("%s %s (%s): go.mod replaced with downstream commit %s\n", branch, repo, status(repo), commit)
+		} else {
+			fmt.Printf("%s %s (%s): must be published before go.mod can replace it, in a later run\n", branch, repo, status(repo))
+			pending = append(pending, repo)
+		}
+	}
+	fmt.Printf("publish order: %s\n", strings.Join(append(pending, "operator-controller"), ", "))
+	return nil
+}
+
+func checkDownstreamBranch(ctx context.Context, logger *logrus.Entry, dir, branch string) {
+	if internal.RefExists(ctx, logger, dir, branch) {
+		return
+	}
+	if defaultBranch, err := internal.DefaultBranch(ctx, logger, dir); err == nil {
+		logger.WithField("downstream-branch", branch).Warnf("downstream branch not found, the default branch of origin is %q: consider --downstream-branch=%s", defaultBranch, defaultBranch)
+	}
+}
+
+func determineDownstreamHead(ctx context.Context, logger *logrus.Entry, dir, repo string, opts Options, fetcher *internal.Fetcher) (string, error) {
+	head, err := fetcher.FetchShallow(ctx, logger, dir, downstreamRemote(repo, opts), "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch upstream: %w", err)
+	}
+	commitSha, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", head,
+	), dir))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse upstream HEAD: %w", err)
+	}
+	return strings.TrimSpace(commitSha), nil
+}
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+var syntheticVersionRegex = regexp.MustCompile(`[^-]+-(?:[0-9]+\.)[0-9]{14}-([0-9a-f]+)`)
+
+// orderedRepos returns the repos in m with operator-controller first and the others by name.
+func orderedRepos[T any](m map[string]T) []string {
+	var repos []string
+	for repo := range m {
+		repos = append(repos, repo)
+	}
+	slices.SortFunc(repos, func(a, b string) int {
+		switch {
+		case a == b:
+			return 0
+		case a == "operator-controller":
+			return -1
+		case b == "operator-controller":
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return repos
+}
+
+func (o *Options) upstreamName(repo string) string {
+	if upstream, ok := o.upstreamNames[repo]; ok {
+		return upstream
+	}
+	return repo
+}
+
+func upstreamRemote(repo string, opts Options) string {
+	repo = opts.upstreamName(repo)
 	mode := flags.FetchMode(opts.FetchMode)
 	switch mode {
 	case flags.SSH:
-		return "git@github.com:operator-framework/" + repo + ".git"
+		return "git@github.com:" + opts.upstreamOrg + "/" + repo + ".git"
 	case flags.HTTPS:
-		return "https://github.com/operator-framework/" + repo + ".git"
+		return "https://github.com/" + opts.upstreamOrg + "/" + repo + ".git"
 	case flags.FILE:
 		path, err := filepath.Abs(opts.FetchDir)
 		if err != nil {
@@ -291,15 +1094,20 @@ func downstreamRemote(repo string, opts Options) string {
 	}
 }
 
-func detectNewCommits(ctx context.Context, logger *logrus.Entry, directories map[string]string, opts Options) (map[string]Config, error) {
-	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "fetch", "--tags", upstreamRemote("operator-controller", opts),
-	), directories["operator-controller"])); err != nil {
+func detectNewCommits(ctx context.Context, logger *logrus.Entry, directories map[string]string, opts Options, fetcher *internal.Fetcher) (map[string]Config, error) {
+	head, err := fetcher.Fetch(ctx, logger, directories["operator-controller"], upstreamRemote("operator-controller", opts), "HEAD")
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch upstream: %w", err)
 	}
+	if override, ok := opts.targetOverrides["operator-controller"]; ok {
+		if err := checkTargetOverride(ctx, logger, "operator-controller", directories["operator-controller"], override, head); err != nil {
+			return nil, err
+		}
+		head = override
+	}
 
 	target := map[string]Config{}
-	config, err, upToDate := detectNewOperatorControllerCommits(ctx, logger, directories["operator-controller"], opts)
+	config, err, upToDate := detectNewOperatorControllerCommits(ctx, logger, directories["operator-controller"], head, opts, fetcher)
 	if err != nil {
 		return nil, err
 	}
@@ -316,30 +1124,49 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, directories map
 		}
 	}
 
+	modules, err := internal.LoadModuleCache(opts.ModuleCacheFile)
+	if err != nil {
+		return nil, err
+	}
 	for _, name := range repoList {
-		module := fmt.Sprintf("github.com/operator-framework/%s", name)
-		rawInfo, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			"go", "list", "-json", "-m", module,
-		), directories["operator-controller"]))
-		if err != nil {
-			return nil, fmt.Errorf("failed to determine dependent version in modules: %w", err)
+		upstream := opts.upstreamName(name)
+		if upstream != name {
+			logger.WithFields(logrus.Fields{"repo": name, "upstream": upstream}).Info("following renamed upstream repository")
 		}
-		var info struct {
-			Version string `json:"Version"`
-		}
-		if err := json.Unmarshal([]byte(rawInfo), &info); err != nil {
-			return nil, fmt.Errorf("failed to parse module version info for %s: %w", module, err)
+		var version string
+		if upstream == "operator-controller" {
+			// the repo was folded into operator-controller, so it follows the same target
+			version = head
+			if config != nil {
+				version = config.Target.Hash
+			}
+		} else {
+			module := fmt.Sprintf("github.com/%s/%s", opts.upstreamOrg, upstream)
+			version, err = modules.Version(ctx, logger, opts.Go(), directories["operator-controller"], "", module, opts.GoEnv())
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine dependent version in modules: %w", err)
+			}
 		}
-		logger.WithFields(logrus.Fields{"repo": name, "version": info.Version}).Info("resolved latest version")
+		logger.WithFields(logrus.Fields{"repo": name, "version": version}).Info("resolved latest version")
 
-		if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			"git", "fetch", "--tags", upstreamRemote(name, opts),
-		), directories[name])); err != nil {
+		upstreamHead, err := fetcher.Fetch(ctx, logger, directories[name], upstreamRemote(name, opts), "HEAD")
+		if err != nil {
 			return nil, fmt.Errorf("failed to fetch upstream version: %w", err)
 		}
+		if override, ok := opts.targetOverrides[name]; ok {
+			if err := checkTargetOverride(ctx, logger, name, directories[name], override, upstreamHead); err != nil {
+				return nil, err
+			}
+			version = override
+		} else if !opts.fetchTags {
+			// the version is usually a tag, but may be a pseudo-version that has none
+			if err := fetcher.FetchTags(ctx, logger, directories[name], upstreamRemote(name, opts), version); err != nil {
+				logger.WithError(err).WithField("version", version).Debug("could not fetch tag for version")
+			}
+		}
 
 		commitSha, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			"git", "rev-parse", info.Version+"^{}", // get the commit the tag points to, if the tag is its own object
+			"git", "rev-parse", version+"^{}", // get the commit the tag points to, if the tag is its own object
 		), directories[name]))
 		if err != nil {
 			// it's possible that the version is synthetic v0.0.0-date-sha, so check for that
@@ -358,28 +1185,56 @@ func detectNewCommits(ctx context.Context, logger *logrus.Entry, directories map
 		commitSha = strings.TrimSpace(commitSha)
 		logger.WithFields(logrus.Fields{"repo": name, "commit": commitSha}).Info("resolved latest commit")
 		commit, err := internal.Info(ctx, logger, commitSha, directories[name])
-		commit.Repo = name
+		commit.Repo = upstream
 		if err != nil {
 			return nil, fmt.Errorf("failed to determine commit info: %w", err)
 		}
-		if !opts.forceRemerge && isUpToDate(ctx, logger, name, directories[name], commit.Hash) {
+		if err := checkExpectedMergeBase(ctx, logger.WithField("repo", name), directories[name], commit.Hash, opts.downstreamBranch, opts.strict); err != nil {
+			return nil, err
+		}
+		if !opts.forceRemerge && isUpToDate(ctx, logger, name, directories[name], commit.Hash, opts.downstreamBranch) {
 			continue
 		}
-		additional, err := detectCarryCommits(ctx, logger, name, directories[name], commit.Hash, opts)
+		additional, dropped, err := detectCarryCommits(ctx, logger, name, directories[name], commit.Hash, opts, fetcher)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.fetchTags {
+			if err := fetcher.FetchTags(ctx, logger, directories[name], upstreamRemote(name, opts)); err != nil {
+				return nil, fmt.Errorf("failed to fetch upstream tags: %w", err)
+			}
+		}
+		tags, err := detectCrossedTags(ctx, logger, name, directories[name], commit.Hash, opts.downstreamBranch)
 		if err != nil {
 			return nil, err
 		}
 		target[name] = Config{
 			Target:     commit,
 			Additional: additional,
+			Tags:       tags,
+			Dropped:    dropped,
 		}
 	}
+	if err := modules.Save(); err != nil {
+		return nil, err
+	}
 	return target, nil
 }
 
-func detectNewOperatorControllerCommits(ctx context.Context, logger *logrus.Entry, dir string, opts Options) (*Config, error, bool) {
+// checkTargetOverride checks that the --target-override of repo is reachable from the upstream head.
+func checkTargetOverride(ctx context.Context, logger *logrus.Entry, repo, dir, override, head string) error {
+	logger.WithFields(logrus.Fields{"repo": repo, "commit": override}).Info("overriding upstream target")
+	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-base", "--is-ancestor", override, head,
+	), dir)); err != nil {
+		return internal.WithExitCode(internal.ExitDivergence, fmt.Errorf("--target-override commit %s is not reachable from the upstream branch of %s: %w", override, repo, err))
+	}
+	return nil
+}
+
+func detectNewOperatorControllerCommits(ctx context.Context, logger *logrus.Entry, dir, head string, opts Options, fetcher *internal.Fetcher) (*Config, error, bool) {
 	commitSha, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "rev-parse", "FETCH_HEAD",
+		"git", "rev-parse", head,
 	), dir))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse upstream HEAD: %w", err), false
@@ -390,23 +1245,110 @@ func detectNewOperatorControllerCommits(ctx context.Context, logger *logrus.Entr
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine commit info: %w", err), false
 	}
-	if isUpToDate(ctx, logger, "operator-controller", dir, commit.Hash) {
+	if err := checkExpectedMergeBase(ctx, logger.WithField("repo", "operator-controller"), dir, commit.Hash, opts.downstreamBranch, opts.strict); err != nil {
+		return nil, err, false
+	}
+	if isUpToDate(ctx, logger, "operator-controller", dir, commit.Hash, opts.downstreamBranch) {
 		return nil, nil, true
 	}
-	additional, err := detectCarryCommits(ctx, logger, "operator-controller", dir, commit.Hash, opts)
+	additional, dropped, err := detectCarryCommits(ctx, logger, "operator-controller", dir, commit.Hash, opts, fetcher)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve additional commits: %w", err), false
 	}
+	if !opts.fetchTags {
+		if err := fetcher.FetchTags(ctx, logger, dir, upstreamRemote("operator-controller", opts)); err != nil {
+			return nil, fmt.Errorf("failed to fetch upstream tags: %w", err), false
+		}
+	}
+	tags, err := detectCrossedTags(ctx, logger, "operator-controller", dir, commit.Hash, opts.downstreamBranch)
+	if err != nil {
+		return nil, err, false
+	}
 	return &Config{
 		Target:     commit,
 		Additional: additional,
+		Tags:       tags,
+		Dropped:    dropped,
 	}, nil, false
 }
 
-func isUpToDate(ctx context.Context, logger *logrus.Entry, repo, dir, commit string) bool {
+func (o *Options) repoLabels(ctx context.Context, logger *logrus.Entry, repo string, config Config, labels []string) []string {
+	if !o.versionRangeLabel {
+		return labels
+	}
+	label := versionRangeLabel(ctx, logger, dirMap[repo], config.Target.Hash, o.downstreamBranch)
+	if label == "" {
+		return labels
+	}
+	return append(slices.Clone(labels), label)
+}
+
+// versionRangeLabel labels the upstream versions moved between, if both are known and differ.
+func versionRangeLabel(ctx context.Context, logger *logrus.Entry, dir, commit, branch string) string {
+	mergeBase, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-base", branch, commit,
+	), dir))
+	if err != nil {
+		logger.WithError(err).Warn("failed to determine merge base, not labelling with the version range")
+		return ""
+	}
+	var versions []string
+	for _, ref := range []string{strings.TrimSpace(mergeBase), commit} {
+		version, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "describe", "--tags", "--abbrev=0", ref,
+		), dir))
+		if err != nil {
+			logger.WithError(err).WithField("ref", ref).Warn("failed to determine upstream version, not labelling with the version range")
+			return ""
+		}
+		versions = append(versions, strings.TrimSpace(version))
+	}
+	if versions[0] == versions[1] {
+		return ""
+	}
+	label := fmt.Sprintf("upstream/%s-to-%s", versions[0], versions[1])
+	// GitHub limits the length of label names
+	if len(label) > 50 {
+		logger.WithField("label", label).Warn("version range label is too long, not labelling")
+		return ""
+	}
+	return label
+}
+
+// detectCrossedTags finds the upstream tags reachable from commit but not from the last synchronized commit.
+func detectCrossedTags(ctx context.Context, logger *logrus.Entry, repo, dir, commit, branch string) ([]string, error) {
+	logger = logger.WithField("repo", repo)
+	mergeBase, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-base", branch, commit,
+	), dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine merge base: %w", err)
+	}
+	rawTags, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "tag",
+		"--merged", commit,
+		"--no-merged", strings.TrimSpace(mergeBase),
+		"--sort=version:refname",
+	), dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine crossed tags: %w", err)
+	}
+	var tags []string
+	for _, tag := range strings.Split(rawTags, "\n") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		logger.WithField("tags", tags).Info("crossing upstream tags")
+	}
+	return tags, nil
+}
+
+func isUpToDate(ctx context.Context, logger *logrus.Entry, repo, dir, commit, branch string) bool {
 	logger = logger.WithField("repo", repo)
 	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "merge-base", "--is-ancestor", commit, "main",
+		"git", "merge-base", "--is-ancestor", commit, branch,
 	), dir)); err == nil {
 		logger.WithField("commit", commit).Info("branch already contains target commit, nothing to do")
 		return true
@@ -414,36 +1356,100 @@ func isUpToDate(ctx context.Context, logger *logrus.Entry, repo, dir, commit str
 	return false
 }
 
-var upstreamCommitRegex = regexp.MustCompile(`^UPSTREAM: (revert: )?(([\w.-]+/[\w-.-]+)?: )?(\d+:|<carry>:|<drop>:)`)
-
-func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, commit string, opts Options) ([]internal.Commit, error) {
-	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-		"git", "fetch", upstreamRemote(repo, opts), commit,
-	), dir)); err != nil {
-		return nil, err
+func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, commit string, opts Options, fetcher *internal.Fetcher) ([]internal.Commit, []internal.DroppedCommit, error) {
+	fetched, err := fetcher.Fetch(ctx, logger, dir, upstreamRemote(repo, opts), commit)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var mergeBase string
 	{
 		mergeBaseRaw, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			"git", "merge-base", "main", "FETCH_HEAD",
+			"git", "merge-base", opts.downstreamBranch, fetched,
 		), dir))
 		if err != nil {
-			return nil, err
+			// a shallow fetch elsewhere may have truncated the history needed to compute the merge-base
+			unshallowed, err2 := internal.Unshallow(ctx, logger, dir, upstreamRemote(repo, opts), commit)
+			if err2 != nil {
+				return nil, nil, err2
+			}
+			if !unshallowed {
+				return nil, nil, err
+			}
+			mergeBaseRaw, err = internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+				"git", "merge-base", opts.downstreamBranch, fetched,
+			), dir))
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 		mergeBase = strings.TrimSpace(mergeBaseRaw)
 	}
 
+	equivalent, err := upstreamEquivalents(ctx, logger, dir, fetched, opts.downstreamBranch)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var downstreamCommits []internal.Commit
+	var dropped []internal.DroppedCommit
 	{
-		rawCommits, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			"git", "log", mergeBase+"..main",
+		merges, err := detectMergeCarries(ctx, logger, dir, mergeBase, opts.downstreamBranch)
+		if err != nil {
+			return nil, nil, err
+		}
+		logArgs := []string{"log", mergeBase + ".." + opts.downstreamBranch,
 			"--ancestry-path", mergeBase,
-			"--no-merges", "--reverse", "--quiet",
+			"--reverse", "--quiet",
 			internal.PrettyFormat,
+		}
+		if !opts.includeMergeCarries {
+			logArgs = append(logArgs, "--no-merges")
+			for merge, files := range merges {
+				if len(files) > 0 {
+					logger.WithFields(logrus.Fields{"commit": merge, "files": files}).Warn("skipping merge commit that made changes of its own, use --include-merge-carries to carry them")
+				}
+			}
+		}
+		rawCommits, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", append(logArgs, opts.PathSpec()...)...,
 		), dir))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if len(opts.PathFilters.Strings()) > 0 {
+			var included []string
+			for _, line := range strings.Split(rawCommits, "\n") {
+				if info, err := internal.ParseFormat(strings.TrimSpace(line)); err == nil {
+					included = append(included, info.Hash)
+				}
+			}
+			// the carries left out by the filter are listed with the others dropped, so the pull request shows them
+			unfiltered, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+				"git", logArgs...,
+			), dir))
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, line := range strings.Split(unfiltered, "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" {
+					continue
+				}
+				info, err := internal.ParseFormat(line)
+				if err != nil {
+					return nil, nil, err
+				}
+				if slices.Contains(included, info.Hash) {
+					continue
+				}
+				info.Repo = repo
+				logger.WithFields(logrus.Fields{"commit": info.Hash, "message": info.Message}).Info("dropping carry that does not touch the filtered paths")
+				dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonPathFilter})
+			}
+			if err := internal.WarnFilteredDependencies(ctx, logger, dir, []string{mergeBase + ".." + opts.downstreamBranch, "--ancestry-path", mergeBase}, included); err != nil {
+				return nil, nil, err
+			}
 		}
 		for _, line := range strings.Split(rawCommits, "\n") {
 			line = strings.TrimSpace(line)
@@ -452,16 +1458,38 @@ func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, co
 			}
 			info, err := internal.ParseFormat(line)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			info.Repo = repo
 			logger = logger.WithFields(logrus.Fields{
 				"commit":  info.Hash,
 				"message": info.Message,
 			})
-			messageMatches := upstreamCommitRegex.FindStringSubmatch(info.Message)
+			if opts.dropPrefix != defaultDropPrefix && strings.HasPrefix(info.Message, opts.dropPrefix) {
+				logger.Info("dropping generated commit")
+				dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonMessage})
+				continue
+			}
+			if files, ok := merges[info.Hash]; ok {
+				if len(files) == 0 {
+					continue
+				}
+				if slices.ContainsFunc(opts.listDropCommits, func(c string) bool { return strings.HasPrefix(info.Hash, c) }) {
+					logger.Info("dropping merge commit due to option")
+					dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonOption})
+					continue
+				}
+				carry, err := extractMergeCarry(ctx, logger, dir, info)
+				if err != nil {
+					return nil, nil, err
+				}
+				logger.WithFields(logrus.Fields{"carry": carry.Hash, "files": files}).Info("carrying merge commit")
+				downstreamCommits = append(downstreamCommits, carry)
+				continue
+			}
+			messageMatches := internal.UpstreamCommitRegex.FindStringSubmatch(info.Message)
 			if len(messageMatches) == 0 || len(messageMatches[0]) == 0 {
-				return nil, fmt.Errorf("unexpected commit message: %s", info.Message)
+				return nil, nil, fmt.Errorf("unexpected commit message: %s", info.Message)
 			}
 
 			drop := ""
@@ -473,14 +1501,42 @@ func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, co
 			}
 			if drop != "" {
 				logger.WithField("option=drop-commits", drop).Info("dropping commit due to option")
+				dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonOption})
 				continue
 			}
 
+			if messageMatches[1] != "" {
+				// a revert of a commit we would otherwise carry cancels it out, so neither needs to be carried
+				reverted := strings.Replace(info.Message, messageMatches[1], "", 1)
+				cancelled := false
+				for i := len(downstreamCommits) - 1; i >= 0; i-- {
+					if downstreamCommits[i].Message == reverted {
+						logger.WithField("reverted", downstreamCommits[i].Hash).Info("dropping commit and its revert")
+						dropped = append(dropped,
+							internal.DroppedCommit{Commit: downstreamCommits[i], Reason: dropReasonRevert},
+							internal.DroppedCommit{Commit: info, Reason: dropReasonRevert},
+						)
+						downstreamCommits = append(downstreamCommits[:i], downstreamCommits[i+1:]...)
+						cancelled = true
+						break
+					}
+				}
+				if cancelled {
+					continue
+				}
+			}
+
 			// TODO: handle reverts, what else?
 			match := strings.Trim(messageMatches[4], "<>:")
+			if match != "drop" && equivalent[info.Hash] {
+				logger.Info("dropping commit whose patch is already present upstream")
+				dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonEquivalent})
+				continue
+			}
 			switch match {
 			case "drop":
 				logger.Info("dropping commit")
+				dropped = append(dropped, internal.DroppedCommit{Commit: info, Reason: dropReasonMessage})
 				continue
 			case "carry":
 				logger.Info("carrying commit")
@@ -503,7 +1559,7 @@ func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, co
 					"git", "log", "--pretty=format:%H", "--grep", fmt.Sprintf("(#%s)", match), commit,
 				), dir))
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 
 				if len(strings.TrimSpace(rawMatches)) == 0 {
@@ -513,160 +1569,115 @@ func detectCarryCommits(ctx context.Context, logger *logrus.Entry, repo, dir, co
 			}
 		}
 	}
-	return downstreamCommits, nil
+	return downstreamCommits, dropped, nil
 }
 
-func applyConfig(ctx context.Context, logger *logrus.Entry, org, repo, branch, dir string, config Config, commitArgs []string, pauseOnCherryPickError, delayManifestGeneration bool) error {
-	// first, get us to the upstream target
-	for _, cmd := range [][]string{
-		{"git", "checkout", branch},
-		{"git", "branch", "synchronize", "--force", config.Target.Hash},
-		{"git", "checkout", "synchronize"},
-		append([]string{"git", "merge", "--strategy", "ours", branch}, commitArgs...),
-	} {
-		if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
-			cmd[0], cmd[1:]...,
-		), dir)); err != nil {
-			return err
+func upstreamEquivalents(ctx context.Context, logger *logrus.Entry, dir, target, downstreamBranch string) (map[string]bool, error) {
+	// with --cherry-mark, the commits that have a patch-equivalent on the other side of the range are marked with =
+	rawMarks, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "log", "--cherry-mark", "--right-only", "--no-merges", "--format=%m%H", target+"..."+downstreamBranch,
+	), dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare patches with upstream: %w", err)
+	}
+	equivalent := map[string]bool{}
+	for _, line := range strings.Fields(rawMarks) {
+		if hash, ok := strings.CutPrefix(line, "="); ok {
+			equivalent[hash] = true
 		}
 	}
+	return equivalent, nil
+}
+
+func applyConfig(ctx context.Context, logger *logrus.Entry, repo, branch, applyBranch, dir string, config Config, opts Options) error {
+	// first, get us to the upstream target
+	if err := checkoutTarget(ctx, logger, dir, opts.downstreamBranch, applyBranch, config.Target.Hash, opts.GitCommitArgs(), opts.GitEnv(), opts.baseMergeStrategy, opts.allowUnrelatedHistories, opts.mergeTrailer()); err != nil {
+		return err
+	}
 
 	// then, cherry-pick the additional bits
 	for _, commit := range config.Additional {
-		cherryPickCommands := []*exec.Cmd{
-			internal.WithDir(exec.CommandContext(ctx,
-				"git", "cherry-pick", commit.Hash,
-			), dir),
-		}
-		goModCommands := []*exec.Cmd{
-			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-				"go", "mod", "tidy",
-			), filepath.Join(dir, "openshift")), os.Environ()...),
-			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-				"go", "mod", "vendor",
-			), filepath.Join(dir, "openshift")), os.Environ()...),
-			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-				"go", "mod", "verify",
-			), filepath.Join(dir, "openshift")), os.Environ()...),
-		}
-		generateManifestsCommands := []*exec.Cmd{
-			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-				"make", "-f", "openshift/Makefile", "manifests",
-			), dir), os.Environ()...),
-		}
-		cleanManifestsCommands := []*exec.Cmd{
-			internal.WithDir(exec.CommandContext(ctx,
-				"git", "rm", "-rf", "--ignore-unmatch", "openshift/manifests",
-			), dir),
+		if err := cherryPickCarry(ctx, logger, dir, nestedModuleMap[repo], commit, opts); err != nil {
+			return err
 		}
+	}
 
-		commitCommands := []*exec.Cmd{
-			internal.WithDir(exec.CommandContext(ctx,
-				"git", "add", "--force", "openshift/.",
-			), dir),
-			// git commit with filenames does not require staging, but since these repos
-			// choose to put vendor in gitignore, we need git add --force to stage those
-			internal.WithDir(exec.CommandContext(ctx,
-				"git", append([]string{"commit", "openshift/.",
-					"--amend",
-					"--no-edit",
-				}, commitArgs...)...,
-			), dir),
-		}
+	// the downstream-owned files are restored once the carries are applied, so that the carries apply as they were
+	if err := internal.RestoreDownstreamFiles(ctx, logger, dir, opts.downstreamBranch, opts.dropPrefix+" restore downstream-owned files", opts.GitGeneratedCommitArgs(), opts.GitEnv()); err != nil {
+		return fmt.Errorf("failed to restore downstream-owned files: %w", err)
+	}
 
-		commands := goModCommands
-		if delayManifestGeneration {
-			commands = append(commands, cleanManifestsCommands...)
-		} else {
-			commands = append(commands, generateManifestsCommands...)
-		}
-		commands = append(commands, commitCommands...)
-
-		// Cherry picking has special error handling
-		for _, cmd := range cherryPickCommands {
-			if msg, err := internal.RunCommand(logger, cmd); err != nil {
-				if pauseOnCherryPickError {
-					fmt.Printf("Error during cherry-pick:\n%s", msg)
-					fmt.Print("Please resolve the cherry-pick conflict. <ENTER> to continue, 'q' to terminate>")
-					text, ioErr := bufio.NewReader(os.Stdin).ReadString('\n')
-					if ioErr != nil || strings.TrimSpace(text) == "q" {
-						return err
-					}
-				} else {
-					return err
-				}
-			}
+	moduleDirs := []string{dir}
+	addFiles := moduleFiles(".", opts.NoVendor)
+	if vendorDirs, ok := extraVendor[repo]; ok {
+		for _, vd := range vendorDirs {
+			moduleDirs = append(moduleDirs, filepath.Join(dir, vd))
+			addFiles = append(addFiles, moduleFiles(vd, opts.NoVendor)...)
 		}
+	}
 
-		// Run the rest of the commands
-		for _, cmd := range commands {
-			if _, err := internal.RunCommand(logger, cmd); err != nil {
-				return err
-			}
-		}
+	goModMessage := opts.dropPrefix + " go mod vendor"
+	if opts.NoVendor {
+		goModMessage = opts.dropPrefix + " go mod tidy"
 	}
 
-	extraVendor := map[string][]string{
-		"operator-controller": {"testdata/push", "testdata/registry"},
+	housekeepingBase, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "rev-parse", "HEAD",
+	), dir))
+	if err != nil {
+		return err
 	}
 
-	generatedPatches := []*exec.Cmd{
-		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-			"go", "mod", "tidy",
-		), dir), os.Environ()...),
-		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-			"go", "mod", "vendor",
-		), dir), os.Environ()...),
-		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-			"go", "mod", "verify",
-		), dir), os.Environ()...),
+	// the go mod commands need to run before we know which of the files they manage exist
+	for _, moduleDir := range moduleDirs {
+		if err := internal.RunGoMod(ctx, logger, opts.Go(), moduleDir, opts.GoEnv(), !opts.NoVendor, opts.GoModRetries); err != nil {
+			return err
+		}
+	}
+	removeGitHubConfig := internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+		"rm", "-rf", ".github",
+	), dir), os.Environ()...)
+	if opts.combineStripCommit {
+		if _, err := internal.RunCommand(logger, removeGitHubConfig); err != nil {
+			return err
+		}
+		addFiles = append(addFiles, ".github")
+		goModMessage += ", remove upstream GitHub configuration"
+	}
+	addFiles, err = internal.PresentPaths(ctx, logger, dir, addFiles)
+	if err != nil {
+		return err
+	}
+	if err := checkModuleDrift(ctx, logger, dir, nestedModuleMap[repo], opts); err != nil {
+		return err
 	}
 
-	addFiles := []string{"vendor", "go.mod", "go.sum"}
-	if vendorDirs, ok := extraVendor[repo]; ok {
-		for _, vd := range vendorDirs {
-			generatedPatches = append(generatedPatches, []*exec.Cmd{
-				internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-					"go", "mod", "tidy",
-				), filepath.Join(dir, vd)), os.Environ()...),
-				internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-					"go", "mod", "vendor",
-				), filepath.Join(dir, vd)), os.Environ()...),
-				internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-					"go", "mod", "verify",
-				), filepath.Join(dir, vd)), os.Environ()...)}...)
-			addFiles = append(addFiles, []string{
-				filepath.Join(vd, "vendor"),
-				filepath.Join(vd, "go.mod"),
-				filepath.Join(vd, "go.sum"),
-			}...)
-		}
-	}
-
-	generatedPatches = append(generatedPatches, []*exec.Cmd{
+	generatedPatches := []*exec.Cmd{
 		// git commit with filenames does not require staging, but since these repos
 		// choose to put vendor in gitignore, we need git add --force to stage those
 		internal.WithDir(exec.CommandContext(ctx,
 			"git", append([]string{"add", "--force"}, addFiles...)...,
 		), dir),
-		internal.WithDir(exec.CommandContext(ctx,
-			"git", append(append([]string{"commit", "--message", "UPSTREAM: <drop>: go mod vendor"},
-				addFiles...), commitArgs...)...,
-		), dir),
 		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-			"rm", "-rf", ".github",
-		), dir), os.Environ()...),
-		internal.WithDir(exec.CommandContext(ctx,
-			"git", "add", "--force",
-			".github",
-		), dir),
-		internal.WithDir(exec.CommandContext(ctx,
-			"git", append([]string{"commit",
+			"git", append(append([]string{"commit", "--message", goModMessage},
+				addFiles...), opts.GitGeneratedCommitArgs()...)...,
+		), dir), opts.GitEnv()...),
+	}
+	if !opts.combineStripCommit {
+		generatedPatches = append(generatedPatches,
+			removeGitHubConfig,
+			internal.WithDir(exec.CommandContext(ctx,
+				"git", "add", "--force",
 				".github",
-				"--message", "UPSTREAM: <drop>: remove upstream GitHub configuration"},
-				commitArgs...)...,
-		), dir),
-	}...)
+			), dir),
+			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+				"git", append([]string{"commit",
+					".github",
+					"--message", opts.dropPrefix + " remove upstream GitHub configuration"},
+					opts.GitGeneratedCommitArgs()...)...,
+			), dir), opts.GitEnv()...),
+		)
+	}
 
 	commitManifests := []*exec.Cmd{
 		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
@@ -677,15 +1688,15 @@ func applyConfig(ctx context.Context, logger *logrus.Entry, org, repo, branch, d
 		), dir),
 		// git commit with filenames does not require staging, but since these repos
 		// choose to put vendor in gitignore, we need git add --force to stage those
-		internal.WithDir(exec.CommandContext(ctx,
+		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
 			"git", append([]string{"commit", "openshift/manifests",
-				"--message", "UPSTREAM: <drop>: Generate manifests",
-			}, commitArgs...)...,
-		), dir),
+				"--message", opts.dropPrefix + " Generate manifests",
+			}, opts.GitGeneratedCommitArgs()...)...,
+		), dir), opts.GitEnv()...),
 	}
 
 	commands := generatedPatches
-	if delayManifestGeneration {
+	if opts.Options.DelayManifestGeneration {
 		commands = append(commands, commitManifests...)
 	}
 
@@ -696,40 +1707,410 @@ func applyConfig(ctx context.Context, logger *logrus.Entry, org, repo, branch, d
 		}
 	}
 
-	return writeCommitCheckerFile(ctx, logger, org, repo, branch, config.Target.Hash, dir, commitArgs)
+	// the target is recorded under the upstream repository's name, which differs from repo when it was renamed
+	upstreamRepo := repo
+	if config.Target.Repo != "" {
+		upstreamRepo = config.Target.Repo
+	}
+	if err := writeCommitCheckerFile(ctx, logger, opts.upstreamOrg, upstreamRepo, branch, config.Target.Hash, dir, opts.GitGeneratedCommitArgs(), opts.GitEnv(), opts.dropPrefix); err != nil {
+		return err
+	}
+
+	if opts.squashHousekeeping {
+		return squashCommits(ctx, logger, dir, strings.TrimSpace(housekeepingBase), opts.dropPrefix+" downstream housekeeping", opts.GitGeneratedCommitArgs(), opts.GitEnv())
+	}
+	return nil
 }
 
-func rewriteGoMod(ctx context.Context, logger *logrus.Entry, dir string, commits map[string]string, commitArgs []string) error {
-	for name, commit := range commits {
+// cherryPickOne cherry-picks just --commit onto the upstream target of repo, pausing on errors.
+func cherryPickOne(ctx context.Context, logger *logrus.Entry, opts Options, repo string, config Config) error {
+	if !logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		logger.Logger.SetLevel(logrus.DebugLevel)
+	}
+	dir := dirMap[repo]
+	if err := internal.SetCommitter(ctx, logger, opts.GitName, opts.GitEmail); err != nil {
+		return fmt.Errorf("failed to set committer: %w", err)
+	}
+	if err := internal.ApplyGitConfig(ctx, logger, dir, opts.GitConfig.Strings()); err != nil {
+		return err
+	}
+
+	// the carries in the plan may differ from the downstream commits, e.g. when they carry merges
+	var carry *internal.Commit
+	for i, commit := range config.Additional {
+		if strings.HasPrefix(commit.Hash, opts.cherryPickCommit) {
+			carry = &config.Additional[i]
+			break
+		}
+	}
+	if carry == nil {
+		commit, err := internal.Info(ctx, logger, opts.cherryPickCommit, dir)
+		if err != nil {
+			return fmt.Errorf("failed to find commit %s: %w", opts.cherryPickCommit, err)
+		}
+		commit.Repo = repo
+		logger.WithField("commit", commit.Hash).Warn("commit is not one of the detected carries")
+		carry = &commit
+	}
+
+	if err := checkoutTarget(ctx, logger, dir, opts.downstreamBranch, synchronizeBranch, config.Target.Hash, opts.GitCommitArgs(), opts.GitEnv(), opts.baseMergeStrategy, opts.allowUnrelatedHistories, opts.mergeTrailer()); err != nil {
+		return fmt.Errorf("failed to check out upstream target: %w", err)
+	}
+	opts.pauseOnCherryPickError = true
+	if err := cherryPickCarry(ctx, logger, dir, nestedModuleMap[repo], *carry, opts); err != nil {
+		return fmt.Errorf("failed to cherry-pick %s: %w", carry.Hash, err)
+	}
+	logger.WithField("commit", carry.Hash).Infof("cherry-picked commit onto upstream target %s on the %s branch", config.Target.Hash, synchronizeBranch)
+	return nil
+}
+
+// rewriteGoModOnly points the go.mod replaces at the downstream HEADs and publishes the result.
+func rewriteGoModOnly(ctx context.Context, logger *logrus.Logger, opts Options, fetcher *internal.Fetcher) error {
+	repo := "operator-controller"
+	dir := dirMap[repo]
+	repoLogger := logger.WithField("repo", repo)
+	if err := internal.SetCommitter(ctx, repoLogger, opts.GitName, opts.GitEmail); err != nil {
+		return fmt.Errorf("failed to set committer: %w", err)
+	}
+	if err := internal.ApplyGitConfig(ctx, repoLogger, dir, opts.GitConfig.Strings()); err != nil {
+		return err
+	}
+	if err := opts.setRepoCommitter(ctx, repoLogger, repo); err != nil {
+		return fmt.Errorf("failed to set committer: %w", err)
+	}
+
+	// with no commits to synchronize, every other repo is replaced with its downstream HEAD
+	replaces, err := downstreamReplaces(ctx, logger, opts, nil, fetcher)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range [][]string{
+		{"git", "checkout", opts.downstreamBranch},
+		{"git", "branch", rewriteGoModBranch, "--force", opts.downstreamBranch},
+		{"git", "checkout", rewriteGoModBranch},
+	} {
+		if _, err := internal.RunCommand(repoLogger, internal.WithDir(exec.CommandContext(ctx,
+			cmd[0], cmd[1:]...,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	if err := rewriteGoMod(ctx, repoLogger, opts.upstreamOrg, dir, replaces, opts.GitGeneratedCommitArgs(), opts.GitEnv(), opts.GoEnv(), opts.Go(), opts.NoVendor, opts.GoModRetries, opts.dropPrefix); err != nil {
+		return fmt.Errorf("failed to rewrite go.mod: %w", err)
+	}
+	changes, err := internal.GoModChanges(ctx, repoLogger, opts.Go(), dir, opts.downstreamBranch, opts.GoEnv())
+	if err != nil {
+		return fmt.Errorf("failed to determine go.mod changes: %w", err)
+	}
+	if len(changes) == 0 {
+		repoLogger.Info("go.mod replaces are up-to-date with the downstream repositories, nothing to push.")
+		return nil
+	}
+
+	client, err := opts.GitHubClient(opts.DryRun)
+	if err != nil {
+		return fmt.Errorf("failed to create a GitHub client: %w", err)
+	}
+	_, err = opts.publishPullRequest(ctx, repoLogger, client, repo, rewriteGoModBranch, opts.IssueRef+": Update Downstream go.mod Replaces", []string{TideMergeMethodMergeLabel, KindSyncLabel}, func(string) (string, error) {
+		return internal.GetGoModBody(changes, opts.assignees(ctx, repoLogger, repo, Config{}))
+	})
+	return err
+}
+
+// publishPullRequest pushes repo to branch in the fork and opens or updates the pull request, returning its link.
+func (o *Options) publishPullRequest(ctx context.Context, logger *logrus.Entry, gc github.Client, repo, branch, title string, prLabels []string, body func(compareHead string) (string, error)) (string, error) {
+	// EnsureFork creates the fork if it does not exist, so when dry-running just assume the default name
+	fork := "operator-framework-" + repo
+	if o.DryRun {
+		logger.Infof("would ensure fork %s/%s", o.GithubLogin, fork)
+	} else {
+		var err error
+		fork, err = gc.EnsureFork(o.GithubLogin, "openshift", "operator-framework-"+repo)
+		if err != nil {
+			return "", fmt.Errorf("could not ensure fork: %w", err)
+		}
+	}
+
+	stdout := bumper.HideSecretsWriter{Delegate: os.Stdout, Censor: secret.Censor}
+	stderr := bumper.HideSecretsWriter{Delegate: os.Stderr, Censor: secret.Censor}
+	remote := fmt.Sprintf(
+		"https://%s:%s@github.com/%s/%s.git",
+		o.GithubLogin, string(secret.GetTokenGenerator(o.GitHubOptions.TokenPath)()), o.GithubLogin, fork,
+	)
+	if err := internal.PushForkBranch(ctx, logger, dirMap[repo], remote, branch, o.downstreamBranch, o.ResetFork, o.NoForcePush, stdout, stderr, o.DryRun); err != nil {
+		return "", fmt.Errorf("Failed to push changes.: %w", err)
+	}
+
+	compareHead := o.GithubLogin + ":" + fork + ":" + branch
+	link := internal.PullRequestURL(o.GithubOrg, "operator-framework-"+repo, o.PRBaseBranch, compareHead, title)
+	text, err := body(compareHead)
+	if err != nil {
+		return "", err
+	}
+	if o.ForkOnly {
+		fmt.Printf("Create the pull request for %s at %s\nwith the following body:\n\n%s\n", repo, link, text)
+		return link, nil
+	}
+
+	var existing *github.Issue
+	if o.CommentOnUpdate {
+		existing, err = internal.FindSyncPR(gc, o.GithubOrg, fork, branch)
+		if err != nil {
+			logger.WithError(err).Warn("failed to find existing pull request")
+		}
+	}
+	if err := bumper.UpdatePullRequestWithLabels(gc, o.GithubOrg, fork, title,
+		text,
+		o.GithubLogin+":"+branch, o.PRBaseBranch, branch, true, prLabels, o.DryRun); err != nil {
+		return "", fmt.Errorf("PR creation failed.: %w", err)
+	}
+	internal.CommentOnUpdate(logger, gc, o.GithubOrg, fork, existing, text, o.DryRun)
+
+	if pr, err := internal.FindSyncPR(gc, o.GithubOrg, fork, branch); err == nil && pr != nil {
+		link = pr.HTMLURL
+	}
+	return link, nil
+}
+
+func checkoutTarget(ctx context.Context, logger *logrus.Entry, dir, downstreamBranch, applyBranch, target string, commitArgs, gitEnv []string, baseMergeStrategy string, allowUnrelatedHistories bool, mergeTrailer string) error {
+	baseCommands := [][]string{
+		{"git", "checkout", downstreamBranch},
+		{"git", "branch", applyBranch, "--force", target},
+		{"git", "checkout", applyBranch},
+	}
+	merge := []string{"git", "merge"}
+	if allowUnrelatedHistories {
+		merge = append(merge, "--allow-unrelated-histories")
+	}
+	switch baseMergeStrategy {
+	case baseMergeStrategyMerge:
+		baseCommands = append(baseCommands, append(append(merge, downstreamBranch), commitArgs...))
+	case baseMergeStrategyReset:
+		baseCommands = append(baseCommands, []string{"git", "reset", "--hard", target})
+	default:
+		baseCommands = append(baseCommands, append(append(merge, "--strategy", "ours", downstreamBranch), commitArgs...))
+	}
+	if mergeTrailer != "" && baseMergeStrategy != baseMergeStrategyReset {
+		// git merge does not take trailers, so they are added to the merge commit afterwards
+		baseCommands = append(baseCommands, []string{"git", "commit", "--amend", "--no-edit", "--trailer", mergeTrailer})
+	}
+	for _, cmd := range baseCommands {
 		if _, err := internal.RunCommand(logger, internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
-			"go", "mod", "edit", "-replace", fmt.Sprintf("github.com/operator-framework/%s=github.com/openshift/operator-framework-%s@%s", name, name, commit),
-		), dir), os.Environ()...)); err != nil {
+			cmd[0], cmd[1:]...,
+		), dir), gitEnv...)); err != nil {
+			if cmd[1] == "merge" {
+				return mergeError(downstreamBranch, target, err)
+			}
 			return err
 		}
-		for _, cmd := range []*exec.Cmd{
-			exec.CommandContext(ctx, "go", "mod", "tidy"),
-			exec.CommandContext(ctx, "go", "mod", "vendor"),
-			exec.CommandContext(ctx, "go", "mod", "verify"),
-		} {
-			if _, err := internal.RunCommand(logger, internal.WithEnv(internal.WithDir(cmd, dir), os.Environ()...)); err != nil {
-				return err
+	}
+	return nil
+}
+
+func mergeError(downstreamBranch, target string, err error) error {
+	if strings.Contains(err.Error(), "refusing to merge unrelated histories") {
+		return internal.WithExitCode(internal.ExitDivergence, fmt.Errorf("failed to merge %s onto upstream target %s, as they share no history: either the upstream history was rewritten, in which case --allow-unrelated-histories merges them anyway, or the clone is shallow or missing history, in which case fetch it in full or re-clone: %w", downstreamBranch, target, err))
+	}
+	return internal.WithExitCode(internal.ExitConflict, fmt.Errorf("failed to merge %s onto upstream target %s: %w", downstreamBranch, target, err))
+}
+
+func cherryPickCarry(ctx context.Context, logger *logrus.Entry, dir, nestedModule string, commit internal.Commit, opts Options) error {
+	span := internal.StartPhase(logger, "cherry-pick", map[string]string{"commit": commit.Hash})
+	err := applyCarry(ctx, logger, dir, nestedModule, commit, opts)
+	span.End(err)
+	return err
+}
+
+func applyCarry(ctx context.Context, logger *logrus.Entry, dir, nestedModule string, commit internal.Commit, opts Options) error {
+	cherryPickCommands := []*exec.Cmd{
+		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+			"git", append(append([]string{"cherry-pick"}, opts.cherryPickArgs()...), commit.Hash)...,
+		), dir), opts.GitEnv()...),
+	}
+	generateManifestsCommands := []*exec.Cmd{
+		internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+			"make", "-f", "openshift/Makefile", "manifests",
+		), dir), os.Environ()...),
+	}
+	cleanManifestsCommands := []*exec.Cmd{
+		internal.WithDir(exec.CommandContext(ctx,
+			"git", "rm", "-rf", "--ignore-unmatch", "openshift/manifests",
+		), dir),
+	}
+
+	// Cherry picking has special error handling
+	skipped := false
+	for _, cmd := range cherryPickCommands {
+		if msg, err := internal.RunCommand(logger, cmd); err != nil {
+			if opts.cherryPickEmpty == cherryPickEmptyDrop && strings.Contains(msg, "The previous cherry-pick is now empty") {
+				logger.WithField("commit", commit.Hash).Info("dropping carry that is now empty")
+				if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+					"git", "cherry-pick", "--skip",
+				), dir)); err != nil {
+					return err
+				}
+				skipped = true
+			} else if opts.pauseOnCherryPickError {
+				fmt.Printf("Error during cherry-pick:\n%s", msg)
+				fmt.Print("Please resolve the cherry-pick conflict. <ENTER> to continue, 'q' to terminate>")
+				text, ioErr := bufio.NewReader(os.Stdin).ReadString('\n')
+				if ioErr != nil || strings.TrimSpace(text) == "q" {
+					return internal.WithExitCode(internal.ExitConflict, err)
+				}
+			} else if opts.ConflictPatchDir != "" {
+				if err := internal.WriteConflictPatch(ctx, logger, dir, opts.ConflictPatchDir, commit.Repo, commit); err != nil {
+					logger.WithError(err).Error("failed to write conflicting patch")
+				}
+				return internal.WithExitCode(internal.ExitConflict, err)
+			} else {
+				return internal.WithExitCode(internal.ExitConflict, err)
 			}
 		}
 	}
 
+	if skipped {
+		return nil
+	}
+
+	if opts.MaxFileSize > 0 {
+		if err := internal.CheckFileSizes(ctx, logger, dir, opts.MaxFileSize, opts.WarnLargeFiles); err != nil {
+			return fmt.Errorf("commit %s is too large: %w", commit.Hash, err)
+		}
+	}
+
+	if opts.handleSubmodules {
+		if err := internal.SyncSubmodules(ctx, logger, dir, opts.GitCarryCommitArgs(), opts.GitEnv()); err != nil {
+			return fmt.Errorf("failed to update submodules: %w", err)
+		}
+	}
+
+	// the nested module is usually added by the carries, so we can only tell whether it exists after picking them
+	if _, err := os.Stat(filepath.Join(dir, nestedModule)); err == nil {
+		if err := internal.RunGoMod(ctx, logger, opts.Go(), filepath.Join(dir, nestedModule), opts.GoEnv(), !opts.NoVendor, opts.GoModRetries); err != nil {
+			return err
+		}
+	} else if os.IsNotExist(err) {
+		logger.WithField("nested-module", nestedModule).Debug("no nested module, skipping go mod commands")
+	} else {
+		return err
+	}
+	var commands []*exec.Cmd
+	if opts.Options.DelayManifestGeneration {
+		commands = append(commands, cleanManifestsCommands...)
+	} else {
+		commands = append(commands, generateManifestsCommands...)
+	}
+
+	commitPaths := []string{"openshift/."}
+	if nestedModule != defaultNestedModule {
+		commitPaths = append(commitPaths, nestedModule+"/.")
+	}
+	commitPaths, err := internal.PresentPaths(ctx, logger, dir, commitPaths)
+	if err != nil {
+		return err
+	}
+	if len(commitPaths) > 0 {
+		commands = append(commands,
+			internal.WithDir(exec.CommandContext(ctx,
+				"git", append([]string{"add", "--force"}, commitPaths...)...,
+			), dir),
+			// git commit with filenames does not require staging, but since these repos
+			// choose to put vendor in gitignore, we need git add --force to stage those
+			internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+				"git", append(append([]string{"-c", "trailer.ifexists=addIfDifferent", "commit"}, commitPaths...), append([]string{
+					"--amend",
+					"--no-edit",
+				}, opts.GitCarryCommitArgs()...)...)...,
+			), dir), opts.GitEnv()...),
+		)
+	}
+
+	// Run the rest of the commands
+	for _, cmd := range commands {
+		if _, err := internal.RunCommand(logger, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func verifyVendor(ctx context.Context, logger *logrus.Entry, repo string, opts Options) error {
+	moduleDirs := []string{dirMap[repo], filepath.Join(dirMap[repo], nestedModuleMap[repo])}
+	for _, vendorDir := range extraVendor[repo] {
+		moduleDirs = append(moduleDirs, filepath.Join(dirMap[repo], vendorDir))
+	}
+	for _, moduleDir := range moduleDirs {
+		if err := internal.VerifyVendor(ctx, logger, opts.Go(), moduleDir, opts.GoEnv(), opts.VerifyBuild); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func promoteScratchBranch(ctx context.Context, logger *logrus.Entry, dir string) error {
+	for _, cmd := range [][]string{
+		{"git", "branch", "--force", synchronizeBranch, scratchBranch},
+		{"git", "checkout", synchronizeBranch},
+		{"git", "branch", "--delete", "--force", scratchBranch},
+	} {
+		if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			cmd[0], cmd[1:]...,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func squashCommits(ctx context.Context, logger *logrus.Entry, dir, base, message string, commitArgs, gitEnv []string) error {
+	for _, cmd := range []*exec.Cmd{
+		exec.CommandContext(ctx,
+			"git", "reset", "--soft", base,
+		),
+		internal.WithEnv(exec.CommandContext(ctx,
+			"git", append([]string{"commit",
+				"--message", message},
+				commitArgs...)...,
+		), gitEnv...),
+	} {
+		if _, err := internal.RunCommand(logger, internal.WithDir(cmd, dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteGoMod replaces the upstream modules in dir with the downstream commits, if there are any.
+func rewriteGoMod(ctx context.Context, logger *logrus.Entry, org, dir string, commits map[string]string, commitArgs, gitEnv, goEnv []string, goBin string, noVendor bool, goModRetries int, dropPrefix string) error {
+	if len(commits) == 0 {
+		logger.Info("no downstream replaces needed")
+		return nil
+	}
+	for name, commit := range commits {
+		if _, err := internal.RunCommand(logger, internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+			goBin, "mod", "edit", "-replace", fmt.Sprintf("github.com/%s/%s=github.com/openshift/operator-framework-%s@%s", org, name, name, commit),
+		), dir), goEnv...)); err != nil {
+			return err
+		}
+		if err := internal.RunGoMod(ctx, logger, goBin, dir, goEnv, !noVendor, goModRetries); err != nil {
+			return err
+		}
+	}
+
+	addFiles, err := internal.PresentPaths(ctx, logger, dir, moduleFiles(".", noVendor))
+	if err != nil {
+		return err
+	}
 	for _, cmd := range []*exec.Cmd{
 		// git commit with filenames does not require staging, but since these repos
 		// choose to put vendor in gitignore, we need git add --force to stage those
 		internal.WithDir(exec.CommandContext(ctx,
-			"git", "add", "--force",
-			"vendor", "go.mod", "go.sum",
+			"git", append([]string{"add", "--force"}, addFiles...)...,
 		), dir),
-		exec.CommandContext(ctx,
-			"git", append([]string{"commit",
-				"vendor", "go.mod", "go.sum",
-				"--message", "UPSTREAM: <drop>: rewrite go mod"},
-				commitArgs...)...,
-		),
+		internal.WithEnv(exec.CommandContext(ctx,
+			"git", append(append([]string{"commit", "--message", dropPrefix + " rewrite go mod"},
+				addFiles...), commitArgs...)...,
+		), gitEnv...),
 	} {
 		if _, err := internal.RunCommand(logger, internal.WithDir(cmd, dir)); err != nil {
 			if strings.Contains(err.Error(), "nothing to commit, working tree clean") {
@@ -742,18 +2123,121 @@ func rewriteGoMod(ctx context.Context, logger *logrus.Entry, dir string, commits
 	return nil
 }
 
-func writeCommitCheckerFile(ctx context.Context, logger *logrus.Entry, org, repo, branch, expectedMergeBase, dir string, commitArgs []string) error {
-	// TODO: move the upstream commit-checker code out of `main` package so we can import this and the regex
-	var config = struct {
-		// UpstreamOrg is the organization of the upstream repository
-		UpstreamOrg string `json:"upstreamOrg,omitempty"`
-		// UpstreamRepo is the repo name of the upstream repository
-		UpstreamRepo string `json:"upstreamRepo,omitempty"`
-		// UpstreamBranch is the branch from the upstream repository we're tracking
-		UpstreamBranch string `json:"upstreamBranch,omitempty"`
-		// ExpectedMergeBase is the latest commit from the upstream that is expected to be present in this downstream
-		ExpectedMergeBase string `json:"expectedMergeBase,omitempty"`
-	}{
+// checkModuleDrift checks that the root and nested modules in dir agree on their shared dependencies.
+func checkModuleDrift(ctx context.Context, logger *logrus.Entry, dir, nestedModule string, opts Options) error {
+	if _, err := os.Stat(filepath.Join(dir, nestedModule, "go.mod")); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	drift, err := internal.ModuleDrift(ctx, logger, opts.Go(), dir, filepath.Join(dir, nestedModule), opts.GoEnv())
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		return nil
+	}
+	var modules []string
+	for _, change := range drift {
+		logger.WithFields(logrus.Fields{"module": change.Module, "root": change.Old, "nested-module": change.New}).Warn("root and nested modules require different versions")
+		modules = append(modules, change.Module)
+	}
+	if opts.strictModules {
+		return fmt.Errorf("root and nested module %s require different versions of %s", nestedModule, strings.Join(modules, ", "))
+	}
+	return nil
+}
+
+func formatPatches(ctx context.Context, logger *logrus.Entry, dir, outDir string, commits []internal.Commit) error {
+	if err := os.MkdirAll(outDir, 0777); err != nil {
+		return err
+	}
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	// patches from a previous run would otherwise be mixed into the series
+	stale, err := filepath.Glob(filepath.Join(outDir, "*.patch"))
+	if err != nil {
+		return err
+	}
+	for _, patch := range stale {
+		if err := os.Remove(patch); err != nil {
+			return err
+		}
+	}
+	for i, commit := range commits {
+		if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "format-patch", "-1", commit.Hash,
+			"--start-number", strconv.Itoa(i+1),
+			"--output-directory", outDir,
+		), dir)); err != nil {
+			return err
+		}
+	}
+	logger.WithFields(logrus.Fields{"dir": outDir, "patches": len(commits)}).Info("wrote patch series")
+	return nil
+}
+
+func moduleFiles(dir string, noVendor bool) []string {
+	files := []string{filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum")}
+	if !noVendor {
+		files = append([]string{filepath.Join(dir, "vendor")}, files...)
+	}
+	return files
+}
+
+// TODO: move the upstream commit-checker code out of `main` package so we can import this and the regex
+type commitCheckerConfig struct {
+	// UpstreamOrg is the organization of the upstream repository
+	UpstreamOrg string `json:"upstreamOrg,omitempty"`
+	// UpstreamRepo is the repo name of the upstream repository
+	UpstreamRepo string `json:"upstreamRepo,omitempty"`
+	// UpstreamBranch is the branch from the upstream repository we're tracking
+	UpstreamBranch string `json:"upstreamBranch,omitempty"`
+	// ExpectedMergeBase is the latest commit from the upstream that is expected to be present in this downstream
+	ExpectedMergeBase string `json:"expectedMergeBase,omitempty"`
+}
+
+func checkExpectedMergeBase(ctx context.Context, logger *logrus.Entry, dir, target, branch string, strict bool) error {
+	raw, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "show", branch+":commitchecker.yaml",
+	), dir))
+	if err != nil {
+		logger.Debug("no commitchecker.yaml on the downstream branch, not checking the recorded merge base")
+		return nil
+	}
+	var config commitCheckerConfig
+	if err := yaml.Unmarshal([]byte(raw), &config); err != nil {
+		return fmt.Errorf("failed to parse commit checker config: %w", err)
+	}
+	if config.ExpectedMergeBase == "" {
+		return nil
+	}
+	logger = logger.WithFields(logrus.Fields{"expected-merge-base": config.ExpectedMergeBase, "target": target})
+
+	var problem string
+	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-base", "--is-ancestor", config.ExpectedMergeBase, branch,
+	), dir)); err != nil {
+		problem = fmt.Sprintf("the merge base %s recorded in commitchecker.yaml is not part of the history of %s", config.ExpectedMergeBase, branch)
+	} else if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-base", "--is-ancestor", config.ExpectedMergeBase, target,
+	), dir)); err != nil {
+		problem = fmt.Sprintf("the upstream target %s is earlier than the merge base %s recorded in commitchecker.yaml", target, config.ExpectedMergeBase)
+	}
+	if problem == "" {
+		return nil
+	}
+	if strict {
+		return internal.WithExitCode(internal.ExitDivergence, fmt.Errorf("%s: was it edited by hand?", problem))
+	}
+	logger.Warnf("%s: was it edited by hand?", problem)
+	return nil
+}
+
+func writeCommitCheckerFile(ctx context.Context, logger *logrus.Entry, org, repo, branch, expectedMergeBase, dir string, commitArgs, gitEnv []string, dropPrefix string) error {
+	config := commitCheckerConfig{
 		UpstreamOrg:       org,
 		UpstreamRepo:      repo,
 		UpstreamBranch:    branch,
@@ -776,12 +2260,12 @@ func writeCommitCheckerFile(ctx context.Context, logger *logrus.Entry, org, repo
 			"git", "add", "--force",
 			"commitchecker.yaml",
 		),
-		exec.CommandContext(ctx,
+		internal.WithEnv(exec.CommandContext(ctx,
 			"git", append([]string{"commit",
 				"commitchecker.yaml",
-				"--message", "UPSTREAM: <drop>: configure the commit-checker"},
+				"--message", dropPrefix + " configure the commit-checker"},
 				commitArgs...)...,
-		),
+		), gitEnv...),
 	} {
 		if _, err := internal.RunCommand(logger, internal.WithDir(cmd, dir)); err != nil {
 			return err
@@ -789,3 +2273,44 @@ func writeCommitCheckerFile(ctx context.Context, logger *logrus.Entry, org, repo
 	}
 	return nil
 }
+
+func runCommitChecker(ctx context.Context, logger *logrus.Entry, dir, start string) error {
+	output, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"sh", "-c", `if [ -f .bingo/variables.env ]; then . .bingo/variables.env; fi; echo "${COMMITCHECKER:-}"`,
+	), dir))
+	if err != nil {
+		return fmt.Errorf("failed to determine commit-checker binary: %w", err)
+	}
+	commitChecker := strings.TrimSpace(output)
+	if commitChecker == "" {
+		commitChecker, err = exec.LookPath("commitchecker")
+		if err != nil {
+			return fmt.Errorf("commit-checker not found in .bingo/variables.env or on the PATH: %w", err)
+		}
+	}
+
+	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		commitChecker, "--start", start, "--end", "HEAD",
+	), dir)); err != nil {
+		return fmt.Errorf("commit-checker rejected the synchronized branch: %w", err)
+	}
+	return nil
+}
+
+func runVerifyCommand(ctx context.Context, logger *logrus.Entry, dir, command string, pauseOnError bool) error {
+	msg, err := internal.RunCommand(logger, internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+		"sh", "-c", command,
+	), dir), os.Environ()...))
+	if err == nil {
+		return nil
+	}
+	if pauseOnError {
+		fmt.Printf("Error during verification:\n%s", msg)
+		fmt.Print("Please fix the synchronized branch. <ENTER> to continue, 'q' to terminate>")
+		text, ioErr := bufio.NewReader(os.Stdin).ReadString('\n')
+		if ioErr == nil && strings.TrimSpace(text) != "q" {
+			return nil
+		}
+	}
+	return fmt.Errorf("verify command %q failed: %w", command, err)
+}
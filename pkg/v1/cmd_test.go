@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/operator-framework-tooling/pkg/flags"
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/openshift/operator-framework-tooling/pkg/internal/gittest"
+	"github.com/sirupsen/logrus"
+)
+
+// newRepo initializes a repository with a single commit of file on branch, isolated from the user's git config.
+func newRepo(t *testing.T, branch, file string) string {
+	t.Helper()
+	gittest.Isolate(t)
+	dir := t.TempDir()
+	gittest.Git(t, dir, "init", "--initial-branch", branch)
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(file+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Git(t, dir, "add", file)
+	gittest.Git(t, dir, "commit", "--message", "add "+file)
+	return dir
+}
+
+func TestCheckoutTargetUnrelatedHistories(t *testing.T) {
+	upstream := newRepo(t, "main", "upstream")
+	downstream := newRepo(t, "downstream", "downstream")
+	gittest.Git(t, downstream, "fetch", upstream, "main")
+	target := gittest.Git(t, downstream, "rev-parse", "FETCH_HEAD")
+
+	ctx := context.Background()
+	logger := logrus.NewEntry(logrus.New())
+	err := checkoutTarget(ctx, logger, downstream, "downstream", synchronizeBranch, target, nil, nil, baseMergeStrategyOurs, false, "")
+	if err == nil {
+		t.Fatal("expected merging unrelated histories to fail")
+	}
+	if code := internal.ExitCodeOf(err); code != internal.ExitDivergence {
+		t.Errorf("expected exit code %d, got %d: %v", internal.ExitDivergence, code, err)
+	}
+
+	if err := checkoutTarget(ctx, logger, downstream, "downstream", synchronizeBranch, target, nil, nil, baseMergeStrategyOurs, true, ""); err != nil {
+		t.Fatalf("expected merging unrelated histories to succeed with allowUnrelatedHistories: %v", err)
+	}
+	if parents := strings.Fields(gittest.Git(t, downstream, "log", "-1", "--format=%P", synchronizeBranch)); len(parents) != 2 || parents[0] != target {
+		t.Errorf("expected a merge commit onto %s, got parents %v", target, parents)
+	}
+}
+
+func TestRunUpToDate(t *testing.T) {
+	dir := newRepo(t, defaultBranch, "downstream")
+	previous := dirMap
+	dirMap = map[string]string{"operator-controller": dir}
+	t.Cleanup(func() { dirMap = previous })
+
+	// a plan without commits is what the detection produces when every repo is already downstreamed
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(plan, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Mode = string(flags.Publish)
+	opts.DryRun = false
+	opts.CommitFileInput = plan
+	opts.GithubOutputFile = ""
+	opts.GitHubOptions.TokenPath = filepath.Join(t.TempDir(), "missing-token")
+
+	if err := Run(context.Background(), logrus.New(), opts); err != nil {
+		t.Fatalf("expected an up-to-date run to succeed without publishing: %v", err)
+	}
+	if branches := gittest.Git(t, dir, "branch", "--list", "--format=%(refname:short)"); branches != defaultBranch {
+		t.Errorf("expected no branch besides %s, got %q", defaultBranch, branches)
+	}
+	if remotes := gittest.Git(t, dir, "remote"); remotes != "" {
+		t.Errorf("expected no remote to have been added to push to, got %q", remotes)
+	}
+}
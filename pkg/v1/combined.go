@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/sirupsen/logrus"
+)
+
+// groupPullRequests groups the repos sharing a checkout, which are published together with --combined-pr.
+func (o *Options) groupPullRequests() error {
+	checkouts := map[string]string{}
+	for _, repo := range orderedRepos(dirMap) {
+		dir, err := filepath.Abs(dirMap[repo])
+		if err != nil {
+			return fmt.Errorf("--%s-dir: %w", repo, err)
+		}
+		checkouts[repo] = dir
+	}
+
+	o.prHosts = map[string]string{}
+	if o.combinedPRManifest == "" {
+		byCheckout := map[string]string{}
+		for _, repo := range orderedRepos(checkouts) {
+			host, ok := byCheckout[checkouts[repo]]
+			if !ok {
+				host = repo
+				byCheckout[checkouts[repo]] = repo
+			}
+			o.prHosts[repo] = host
+		}
+		return nil
+	}
+
+	raw, err := os.ReadFile(o.combinedPRManifest)
+	if err != nil {
+		return fmt.Errorf("--combined-pr-manifest: %w", err)
+	}
+	var manifest map[string][]string
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("--combined-pr-manifest: %w", err)
+	}
+	for _, host := range orderedRepos(manifest) {
+		for _, repo := range append([]string{host}, manifest[host]...) {
+			if _, known := dirMap[repo]; !known {
+				return fmt.Errorf("--combined-pr-manifest: unknown repo %q", repo)
+			}
+			if other, grouped := o.prHosts[repo]; grouped && other != host {
+				return fmt.Errorf("--combined-pr-manifest: repo %q is published by both %q and %q", repo, other, host)
+			}
+			if checkouts[repo] != checkouts[host] {
+				return fmt.Errorf("--combined-pr-manifest: %s and %s must share a checkout of their downstream repository to be published in one pull request", host, repo)
+			}
+			o.prHosts[repo] = host
+		}
+	}
+	for repo := range dirMap {
+		if _, grouped := o.prHosts[repo]; !grouped {
+			o.prHosts[repo] = repo
+		}
+	}
+	return nil
+}
+
+// prHost returns the repo whose pull request publishes repo.
+func (o *Options) prHost(repo string) string {
+	if host, ok := o.prHosts[repo]; ok && o.combinedPR {
+		return host
+	}
+	return repo
+}
+
+// pullRequests groups the repos with changes by the repo whose pull request publishes them.
+func (o *Options) pullRequests(commits map[string]Config) map[string][]string {
+	groups := map[string][]string{}
+	for _, repo := range orderedRepos(commits) {
+		host := o.prHost(repo)
+		groups[host] = append(groups[host], repo)
+	}
+	return groups
+}
+
+// combineConfigs merges the plans of the repos published in one pull request, which must share their target.
+func combineConfigs(repos []string, commits map[string]Config) (Config, error) {
+	first := commits[repos[0]]
+	combined := Config{
+		Target:     first.Target,
+		Additional: slices.Clone(first.Additional),
+		Tags:       slices.Clone(first.Tags),
+		Dropped:    slices.Clone(first.Dropped),
+	}
+	for _, repo := range repos[1:] {
+		config := commits[repo]
+		if config.Target.Hash != combined.Target.Hash {
+			return Config{}, internal.WithExitCode(internal.ExitConfig, fmt.Errorf("cannot publish %s and %s in one pull request, as they share a checkout but synchronize to different upstream targets %s and %s", repos[0], repo, combined.Target.Hash, config.Target.Hash))
+		}
+		for _, commit := range config.Additional {
+			if !slices.ContainsFunc(combined.Additional, func(other internal.Commit) bool { return other.Hash == commit.Hash }) {
+				combined.Additional = append(combined.Additional, commit)
+			}
+		}
+		for _, tag := range config.Tags {
+			if !slices.Contains(combined.Tags, tag) {
+				combined.Tags = append(combined.Tags, tag)
+			}
+		}
+		for _, drop := range config.Dropped {
+			if !slices.ContainsFunc(combined.Dropped, func(other internal.DroppedCommit) bool { return other.Hash == drop.Hash }) {
+				combined.Dropped = append(combined.Dropped, drop)
+			}
+		}
+	}
+	return combined, nil
+}
+
+// pullRequestBody renders the body of the pull request publishing repos.
+func (o *Options) pullRequestBody(ctx context.Context, logger *logrus.Entry, tmpl *template.Template, repos []string, commits map[string]Config, compareHead string) (string, error) {
+	host := repos[0]
+	dir := dirMap[host]
+	changes, err := internal.GoModChanges(ctx, logger, o.Go(), dir, o.downstreamBranch, o.GoEnv())
+	if err != nil {
+		logger.WithError(err).Warn("failed to determine go.mod changes")
+	}
+	stat := o.GetDiffStat(ctx, logger, dir, o.downstreamBranch)
+	if len(repos) == 1 {
+		config := commits[host]
+		return internal.GetBodyV1(tmpl, o.upstreamOrg, config.Target, config.Tags, config.Additional, o.bodyMaxCarries, config.Dropped, changes, stat, o.PRBaseBranch, compareHead, o.assignees(ctx, logger, host, config))
+	}
+	var bodies []internal.RepoBodyV1
+	var assign []string
+	for _, repo := range repos {
+		config := commits[repo]
+		bodies = append(bodies, internal.RepoBodyV1{Repo: repo, Target: config.Target, Tags: config.Tags, Commits: config.Additional, Dropped: config.Dropped})
+		for _, who := range o.assignees(ctx, logger, repo, config) {
+			if !slices.Contains(assign, who) {
+				assign = append(assign, who)
+			}
+		}
+	}
+	return internal.GetCombinedBodyV1(tmpl, o.upstreamOrg, bodies, o.bodyMaxCarries, changes, stat, o.PRBaseBranch, compareHead, assign)
+}
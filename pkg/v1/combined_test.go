@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+)
+
+func TestGroupPullRequests(t *testing.T) {
+	monorepo := t.TempDir()
+	other := t.TempDir()
+	for _, tc := range []struct {
+		name     string
+		dirs     map[string]string
+		manifest string
+		expected map[string]string
+		err      string
+	}{
+		{
+			name:     "separate checkouts are published separately",
+			dirs:     map[string]string{"operator-controller": monorepo, "catalogd": other},
+			expected: map[string]string{"operator-controller": "operator-controller", "catalogd": "catalogd"},
+		},
+		{
+			name:     "a shared checkout is published by the first repo",
+			dirs:     map[string]string{"operator-controller": monorepo, "catalogd": monorepo + "/"},
+			expected: map[string]string{"operator-controller": "operator-controller", "catalogd": "operator-controller"},
+		},
+		{
+			name:     "the manifest chooses the repo publishing a shared checkout",
+			dirs:     map[string]string{"operator-controller": monorepo, "catalogd": monorepo},
+			manifest: `{"catalogd": ["operator-controller"]}`,
+			expected: map[string]string{"operator-controller": "catalogd", "catalogd": "catalogd"},
+		},
+		{
+			name:     "the manifest cannot group separate checkouts",
+			dirs:     map[string]string{"operator-controller": monorepo, "catalogd": other},
+			manifest: `{"operator-controller": ["catalogd"]}`,
+			err:      "must share a checkout",
+		},
+		{
+			name:     "the manifest cannot publish a repo twice",
+			dirs:     map[string]string{"operator-controller": monorepo, "catalogd": monorepo},
+			manifest: `{"operator-controller": ["catalogd"], "catalogd": []}`,
+			err:      "published by both",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := dirMap
+			dirMap = tc.dirs
+			t.Cleanup(func() { dirMap = previous })
+
+			opts := DefaultOptions()
+			opts.combinedPR = true
+			if tc.manifest != "" {
+				opts.combinedPRManifest = filepath.Join(t.TempDir(), "manifest.json")
+				if err := os.WriteFile(opts.combinedPRManifest, []byte(tc.manifest), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := opts.groupPullRequests()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected an error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for repo, host := range tc.expected {
+				if got := opts.prHost(repo); got != host {
+					t.Errorf("expected %s to be published by %s, got %s", repo, host, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCombineConfigs(t *testing.T) {
+	target := internal.Commit{Hash: "1111111111111111111111111111111111111111", Repo: "operator-controller"}
+	carry := func(hash string) internal.Commit {
+		return internal.Commit{Hash: strings.Repeat(hash, 40), Repo: "operator-controller"}
+	}
+	commits := map[string]Config{
+		"operator-controller": {Target: target, Additional: []internal.Commit{carry("a"), carry("b")}, Tags: []string{"v1.0.0"}},
+		"catalogd":            {Target: target, Additional: []internal.Commit{carry("b"), carry("c")}, Tags: []string{"v1.0.0"}, Dropped: []internal.DroppedCommit{{Commit: carry("d"), Reason: dropReasonMessage}}},
+	}
+	combined, err := combineConfigs([]string{"operator-controller", "catalogd"}, commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	for _, commit := range combined.Additional {
+		hashes = append(hashes, commit.Hash[:1])
+	}
+	if strings.Join(hashes, "") != "abc" {
+		t.Errorf("expected each carry to be applied once in order, got %v", hashes)
+	}
+	if len(combined.Tags) != 1 || len(combined.Dropped) != 1 {
+		t.Errorf("expected the tags and dropped commits to be merged, got %v and %v", combined.Tags, combined.Dropped)
+	}
+	if len(commits["operator-controller"].Additional) != 2 {
+		t.Errorf("expected the plan of the first repo to be left as is, got %v", commits["operator-controller"].Additional)
+	}
+
+	commits["catalogd"] = Config{Target: internal.Commit{Hash: "2222222222222222222222222222222222222222"}}
+	_, err = combineConfigs([]string{"operator-controller", "catalogd"}, commits)
+	if code := internal.ExitCodeOf(err); code != internal.ExitConfig {
+		t.Errorf("expected exit code %d for different targets, got %d: %v", internal.ExitConfig, code, err)
+	}
+}
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/sirupsen/logrus"
+)
+
+// detectMergeCarries finds the merge commits between mergeBase and head, along with the files each of them changed
+// on its own, beyond merging its parents - for instance, downstream-only fixes made while resolving a merge. Those
+// changes are not part of any linear commit, so they are lost unless the merge is carried.
+func detectMergeCarries(ctx context.Context, logger *logrus.Entry, dir, mergeBase, head string) (map[string][]string, error) {
+	rawMerges, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "rev-list", "--merges", "--ancestry-path", mergeBase+".."+head,
+	), dir))
+	if err != nil {
+		return nil, err
+	}
+	merges := map[string][]string{}
+	for _, merge := range strings.Fields(rawMerges) {
+		// a combined diff only shows the changes that match none of the parents, so merges that resolved cleanly or
+		// with one side's content (including our synchronization merges) show nothing
+		rawFiles, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "diff-tree", "--cc", "--no-commit-id", "--name-only", merge,
+		), dir))
+		if err != nil {
+			return nil, err
+		}
+		merges[merge] = strings.Fields(rawFiles)
+	}
+	return merges, nil
+}
+
+// extractMergeCarry creates a commit holding the changes merge made on top of the automatic merge of its parents,
+// so that they can be cherry-picked like any other carry. The commit is kept alive under refs/merge-carries/.
+func extractMergeCarry(ctx context.Context, logger *logrus.Entry, dir string, merge internal.Commit) (internal.Commit, error) {
+	rawParents, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "show", "--no-patch", "--format=%P", merge.Hash,
+	), dir))
+	if err != nil {
+		return internal.Commit{}, err
+	}
+	parents := strings.Fields(rawParents)
+	if len(parents) != 2 {
+		return internal.Commit{}, fmt.Errorf("merge commit %s has %d parents, only two-parent merges can be carried", merge.Hash, len(parents))
+	}
+
+	// conflicts do not prevent the tree from being written, they just leave the markers in it
+	rawTree, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "merge-tree", "--write-tree", "--no-messages", parents[0], parents[1],
+	), dir))
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return internal.Commit{}, err
+	}
+	tree, _, _ := strings.Cut(rawTree, "\n")
+
+	automatic, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "commit-tree", strings.TrimSpace(tree), "-p", parents[0], "-m", "automatic merge of "+merge.Hash,
+	), dir))
+	if err != nil {
+		return internal.Commit{}, err
+	}
+
+	message := merge.Message
+	if !internal.UpstreamCommitRegex.MatchString(message) {
+		message = "UPSTREAM: <carry>: " + message
+	}
+	rawAuthor, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "show", "--no-patch", "--format=%an%n%ae%n%aI", merge.Hash,
+	), dir))
+	if err != nil {
+		return internal.Commit{}, err
+	}
+	author := strings.Split(strings.TrimSpace(rawAuthor), "\n")
+	if len(author) != 3 {
+		return internal.Commit{}, fmt.Errorf("unexpected author of merge commit %s: %q", merge.Hash, rawAuthor)
+	}
+	rawCarry, err := internal.RunCommand(logger, internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+		"git", "commit-tree", merge.Hash+"^{tree}", "-p", strings.TrimSpace(automatic),
+		"-m", message, "-m", "Carries the changes made by merge commit "+merge.Hash+".",
+	), dir), append(os.Environ(), "GIT_AUTHOR_NAME="+author[0], "GIT_AUTHOR_EMAIL="+author[1], "GIT_AUTHOR_DATE="+author[2])...))
+	if err != nil {
+		return internal.Commit{}, err
+	}
+	carry := strings.TrimSpace(rawCarry)
+
+	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "update-ref", "refs/merge-carries/"+merge.Hash, carry,
+	), dir)); err != nil {
+		return internal.Commit{}, err
+	}
+	merge.Hash = carry
+	merge.Message = message
+	return merge, nil
+}
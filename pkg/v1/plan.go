@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/sirupsen/logrus"
+)
+
+const planHelp = `
+# Commands:
+# p, pick <commit> = carry the commit
+# d, drop <commit> = do not carry the commit
+#
+# These lines can be re-ordered; they are cherry-picked from top to bottom.
+# Lines starting with '#' are ignored. Removing a line drops the commit.
+`
+
+// editPlan lets the user edit the list of commits to carry for each repo in $EDITOR, in the style of
+// `git rebase -i`.
+func editPlan(ctx context.Context, logger *logrus.Logger, commits map[string]Config) error {
+	for _, repo := range orderedRepos(commits) {
+		config := commits[repo]
+		repoLogger := logger.WithField("repo", repo)
+		additional, err := editRepoPlan(ctx, repoLogger, repo, dirMap[repo], config.Additional)
+		if err != nil {
+			return fmt.Errorf("failed to edit plan for %s: %w", repo, err)
+		}
+		config.Additional = additional
+		commits[repo] = config
+	}
+	return nil
+}
+
+func editRepoPlan(ctx context.Context, logger *logrus.Entry, repo, dir string, additional []internal.Commit) ([]internal.Commit, error) {
+	planFile, err := os.CreateTemp("", "carry-plan-"+repo)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(planFile.Name())
+
+	var lines []string
+	for _, commit := range additional {
+		lines = append(lines, fmt.Sprintf("pick %s %s", commit.Hash, commit.Message))
+	}
+	lines = append(lines, fmt.Sprintf("\n# Carry plan for openshift/operator-framework-%s (%d commits)", repo, len(additional)))
+	if _, err := planFile.WriteString(strings.Join(lines, "\n") + "\n" + planHelp); err != nil {
+		return nil, err
+	}
+	if err := planFile.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	editorCmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$0"`, planFile.Name())
+	editorCmd.Stdin, editorCmd.Stdout, editorCmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run editor: %w", err)
+	}
+
+	rawPlan, err := os.ReadFile(planFile.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var edited []internal.Commit
+	for _, line := range strings.Split(string(rawPlan), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid plan line: %q", line)
+		}
+		switch fields[0] {
+		case "p", "pick":
+		case "d", "drop":
+			logger.WithField("commit", fields[1]).Info("dropping commit due to edited plan")
+			continue
+		default:
+			return nil, fmt.Errorf("unknown plan command %q in line: %q", fields[0], line)
+		}
+
+		commitSha, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "rev-parse", "--verify", fields[1]+"^{commit}",
+		), dir))
+		if err != nil {
+			return nil, fmt.Errorf("commit %s in plan does not exist: %w", fields[1], err)
+		}
+		commit, err := internal.Info(ctx, logger, strings.TrimSpace(commitSha), dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine commit info: %w", err)
+		}
+		commit.Repo = repo
+		edited = append(edited, commit)
+	}
+	return edited, nil
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/openshift/operator-framework-tooling/pkg/internal"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	precheckClean    = "applies"
+	precheckEmpty    = "empty"
+	precheckConflict = "conflicts"
+)
+
+// precheckCarries test-applies each carry on top of the target commit in a scratch worktree, recording
+// whether it applies cleanly, conflicts, or is empty. The real branch is not modified.
+func precheckCarries(ctx context.Context, logger *logrus.Entry, dir string, config *Config) error {
+	worktree, err := os.MkdirTemp("", "precheck")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(worktree)
+	if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+		"git", "worktree", "add", "--detach", worktree, config.Target.Hash,
+	), dir)); err != nil {
+		return fmt.Errorf("failed to create scratch worktree: %w", err)
+	}
+	defer func() {
+		if _, err := internal.RunCommand(logger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "worktree", "remove", "--force", worktree,
+		), dir)); err != nil {
+			logger.WithError(err).Warn("failed to remove scratch worktree")
+		}
+	}()
+
+	for i, commit := range config.Additional {
+		commitLogger := logger.WithField("commit", commit.Hash)
+		if _, err := internal.RunCommand(commitLogger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "cherry-pick", "--no-commit", commit.Hash,
+		), worktree)); err != nil {
+			commitLogger.Warn("carry does not apply cleanly to the new target")
+			config.Additional[i].Precheck = precheckConflict
+			if _, err := internal.RunCommand(commitLogger, internal.WithDir(exec.CommandContext(ctx,
+				"git", "reset", "--hard", "HEAD",
+			), worktree)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := internal.RunCommand(commitLogger, internal.WithDir(exec.CommandContext(ctx,
+			"git", "diff", "--cached", "--quiet",
+		), worktree)); err == nil {
+			commitLogger.Info("carry is empty against the new target")
+			config.Additional[i].Precheck = precheckEmpty
+			continue
+		}
+
+		config.Additional[i].Precheck = precheckClean
+		// commit the carry so that later carries are checked against it
+		if _, err := internal.RunCommand(commitLogger, internal.WithEnv(internal.WithDir(exec.CommandContext(ctx,
+			"git", "commit", "--quiet", "--no-verify", "--reuse-message", commit.Hash,
+		), worktree), append(os.Environ(), "GIT_COMMITTER_NAME=precheck", "GIT_COMMITTER_EMAIL=precheck@localhost")...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}